@@ -3,3 +3,17 @@ module motor-town-server-tool
 go 1.23.5
 
 require github.com/BurntSushi/toml v1.3.2
+
+require (
+	github.com/gofrs/flock v0.12.1
+	github.com/peterh/liner v1.2.2
+	golang.org/x/crypto v0.40.0
+	golang.org/x/term v0.33.0
+	golang.org/x/text v0.27.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/mattn/go-runewidth v0.0.3 // indirect
+	golang.org/x/sys v0.34.0 // indirect
+)
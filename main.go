@@ -3,19 +3,30 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"motor-town-server-tool/modules/config"
+	"motor-town-server-tool/modules/exitcode"
 	"motor-town-server-tool/modules/loader"
+	"motor-town-server-tool/modules/output"
 )
 
 func main() {
 	commands := loader.LoadCommands()
 
-	if len(os.Args) < 2 {
+	args, err := parseGlobalOptions(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		printUsage(commands)
+		os.Exit(exitcode.Usage)
+	}
+
+	if len(args) < 1 {
 		printUsage(commands)
 		os.Exit(1)
 	}
 
-	commandName := os.Args[1]
+	commandName := args[0]
 
 	if commandName == "help" || commandName == "-h" || commandName == "--help" {
 		printUsage(commands)
@@ -29,22 +40,55 @@ func main() {
 		os.Exit(1)
 	}
 
-	args := []string{}
-	if len(os.Args) > 2 {
-		args = os.Args[2:]
+	if err := command.Execute(args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitcode.FromError(err))
 	}
+}
 
-	if err := command.Execute(args); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+func parseGlobalOptions(args []string) ([]string, error) {
+	for len(args) > 0 {
+		name, value, hasValue := strings.Cut(args[0], "=")
+
+		switch name {
+		case "-c", "--config", "-o", "--output":
+			if !hasValue {
+				if len(args) < 2 {
+					return nil, fmt.Errorf("%s requires a value", name)
+				}
+				value = args[1]
+				args = args[1:]
+			}
+		default:
+			return args, nil
+		}
+
+		switch name {
+		case "-c", "--config":
+			config.SetPath(value)
+		case "-o", "--output":
+			format, err := output.ParseFormat(value)
+			if err != nil {
+				return nil, err
+			}
+			output.SetFormat(format)
+		}
+
+		args = args[1:]
 	}
+
+	return args, nil
 }
 
 func printUsage(commands map[string]loader.Commander) {
 	fmt.Println("Motor Town Server Tool")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Println("  motor-town-server-tool <command> [args...]")
+	fmt.Println("  motor-town-server-tool [options] <command> [args...]")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  -c, --config <path>    Configuration file (default: $MTST_CONFIG or discovered)")
+	fmt.Println("  -o, --output <format>  Output format: table (default), json, yaml or csv")
 	fmt.Println()
 	fmt.Println("Commands:")
 
@@ -56,4 +100,8 @@ func printUsage(commands map[string]loader.Commander) {
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  motor-town-server-tool configure")
+	fmt.Println("  motor-town-server-tool configure add production --ip 192.168.1.100 --port 8080 --password-env MT_PASSWORD")
+	fmt.Println("  motor-town-server-tool check all")
+	fmt.Println("  motor-town-server-tool exec production kick 76561198000000000")
+	fmt.Println("  motor-town-server-tool --output json exec production players")
 }
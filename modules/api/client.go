@@ -1,8 +1,12 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"time"
@@ -10,156 +14,227 @@ import (
 	"motor-town-server-tool/modules/types"
 )
 
-type APIResponse struct {
-	Data      interface{} `json:"data"`
-	Message   string      `json:"message"`
-	Succeeded bool        `json:"succeeded"`
-}
+const (
+	DefaultTimeout   = 10 * time.Second
+	DefaultUserAgent = "motor-town-server-tool"
+)
 
-type VersionData struct {
-	Version string `json:"version"`
+type Client struct {
+	baseURL    string
+	password   string
+	httpClient *http.Client
+	userAgent  string
+	logger     *log.Logger
+	retry      RetryPolicy
 }
 
-type PlayerCountData struct {
-	NumPlayers int `json:"num_players"`
-}
+type Option func(*Client)
 
-type Player struct {
-	Name     string `json:"name"`
-	UniqueID string `json:"unique_id"`
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient.Timeout = timeout
+	}
 }
 
-type HousingData struct {
-	OwnerUniqueID string `json:"owner_unique_id"`
-	ExpireTime    string `json:"expire_time"`
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.httpClient.Transport = transport
+	}
 }
 
-func makeGETRequest(instance types.Instance, endpoint string, extraParams map[string]string) (*APIResponse, error) {
-	baseURL := fmt.Sprintf("http://%s:%d", instance.IP, instance.Port)
-
-	params := url.Values{}
-	params.Set("password", instance.Password)
-
-	for key, value := range extraParams {
-		params.Set(key, value)
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
 	}
+}
 
-	fullURL := fmt.Sprintf("%s%s?%s", baseURL, endpoint, params.Encode())
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
 	}
+}
 
-	resp, err := client.Get(fullURL)
+func NewClient(instance types.Instance, opts ...Option) (*Client, error) {
+	transport, err := transportFor(instance)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var apiResp APIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, fmt.Errorf("invalid TLS settings: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return &apiResp, fmt.Errorf("HTTP %d: %s", resp.StatusCode, apiResp.Message)
+	c := &Client{
+		baseURL:  instance.BaseURL(),
+		password: instance.Password,
+		httpClient: &http.Client{
+			Timeout:   DefaultTimeout,
+			Transport: transport,
+		},
+		userAgent: DefaultUserAgent,
+		retry:     RetryPolicyFromConfig(instance.Retry),
 	}
 
-	if !apiResp.Succeeded {
-		return &apiResp, fmt.Errorf("API call failed: %s", apiResp.Message)
+	for _, opt := range opts {
+		opt(c)
 	}
 
-	return &apiResp, nil
+	return c, nil
 }
 
-func makePOSTRequest(instance types.Instance, endpoint string, extraParams map[string]string) (*APIResponse, error) {
-	baseURL := fmt.Sprintf("http://%s:%d", instance.IP, instance.Port)
+func (c *Client) do(ctx context.Context, method, endpoint string, extraParams map[string]string) (*APIResponse, error) {
+	attempts := c.retry.attempts(method)
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.doOnce(ctx, method, endpoint, extraParams)
+		if err == nil || attempt >= attempts || !isRetryable(err) {
+			return resp, err
+		}
 
+		delay := c.retry.backoff(attempt)
+		c.logf("%s %s attempt %d/%d failed, retrying in %s: %v", method, endpoint, attempt, attempts, delay, err)
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, fmt.Errorf("request aborted: %w", err)
+		}
+	}
+}
+
+func (c *Client) doOnce(ctx context.Context, method, endpoint string, extraParams map[string]string) (*APIResponse, error) {
 	params := url.Values{}
-	params.Set("password", instance.Password)
+	params.Set("password", c.password)
 
 	for key, value := range extraParams {
 		params.Set(key, value)
 	}
 
-	fullURL := fmt.Sprintf("%s%s?%s", baseURL, endpoint, params.Encode())
+	fullURL := fmt.Sprintf("%s%s?%s", c.baseURL, endpoint, params.Encode())
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", redactURLError(err, c.baseURL+endpoint))
 	}
+	req.Header.Set("User-Agent", c.userAgent)
 
-	req, err := http.NewRequest("POST", fullURL, nil)
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		c.logf("%s %s failed after %s: %v", method, endpoint, time.Since(start), err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("request aborted: %w", ctxErr)
+		}
+		if isCertificateError(err) {
+			return nil, &CertificateError{Endpoint: endpoint, Err: redactURLError(err, c.baseURL+endpoint)}
+		}
+		return nil, &UnreachableError{Endpoint: endpoint, Err: redactURLError(err, c.baseURL+endpoint)}
 	}
+	defer resp.Body.Close()
 
-	resp, err := client.Do(req)
+	c.logf("%s %s -> %d in %s", method, endpoint, resp.StatusCode, time.Since(start))
+
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, &UnreachableError{Endpoint: endpoint, Err: redactURLError(err, c.baseURL+endpoint)}
 	}
-	defer resp.Body.Close()
 
 	var apiResp APIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
+	decodeErr := json.Unmarshal(body, &apiResp)
 
 	if resp.StatusCode != http.StatusOK {
-		return &apiResp, fmt.Errorf("HTTP %d: %s", resp.StatusCode, apiResp.Message)
+		return nil, &HTTPError{Endpoint: endpoint, StatusCode: resp.StatusCode, Message: apiResp.Message}
+	}
+
+	if decodeErr != nil {
+		return nil, &DecodeError{Endpoint: endpoint, Err: decodeErr}
 	}
 
 	if !apiResp.Succeeded {
-		return &apiResp, fmt.Errorf("API call failed: %s", apiResp.Message)
+		return nil, &APIError{Endpoint: endpoint, Message: apiResp.Message}
 	}
 
 	return &apiResp, nil
 }
 
-func SendChatMessage(instance types.Instance, message string) (*APIResponse, error) {
+// redactURLError strips the query string, which carries the server
+// password, from errors produced by the HTTP client.
+func redactURLError(err error, safeURL string) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return &url.Error{Op: urlErr.Op, URL: safeURL, Err: urlErr.Err}
+	}
+	return err
+}
+
+func (c *Client) logf(format string, args ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf(format, args...)
+	}
+}
+
+func (c *Client) SendChatMessage(ctx context.Context, message string) (string, error) {
 	if message == "" {
-		return nil, fmt.Errorf("message cannot be empty")
+		return "", fmt.Errorf("message cannot be empty")
 	}
 
 	params := map[string]string{
 		"message": message,
 	}
-	return makePOSTRequest(instance, "/chat", params)
+	return c.post(ctx, "/chat", params)
 }
 
-func GetPlayerCount(instance types.Instance) (*APIResponse, error) {
-	return makeGETRequest(instance, "/player/count", nil)
+func (c *Client) GetPlayerCount(ctx context.Context) (int, error) {
+	data, err := get[PlayerCountData](ctx, c, "/player/count")
+	if err != nil {
+		return 0, err
+	}
+	return data.NumPlayers, nil
 }
 
-func GetPlayerList(instance types.Instance) (*APIResponse, error) {
-	return makeGETRequest(instance, "/player/list", nil)
+func (c *Client) GetPlayerList(ctx context.Context) ([]Player, error) {
+	data, err := get[PlayerSet](ctx, c, "/player/list")
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
 }
 
-func GetBanList(instance types.Instance) (*APIResponse, error) {
-	return makeGETRequest(instance, "/player/banlist", nil)
+func (c *Client) GetBanList(ctx context.Context) ([]Player, error) {
+	data, err := get[PlayerSet](ctx, c, "/player/banlist")
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
 }
 
-func GetVersion(instance types.Instance) (*APIResponse, error) {
-	return makeGETRequest(instance, "/version", nil)
+func (c *Client) GetVersion(ctx context.Context) (string, error) {
+	data, err := get[VersionData](ctx, c, "/version")
+	if err != nil {
+		return "", err
+	}
+	return data.Version, nil
 }
 
-func GetHousingList(instance types.Instance) (*APIResponse, error) {
-	return makeGETRequest(instance, "/housing/list", nil)
+func (c *Client) GetHousingList(ctx context.Context) (map[string]HousingData, error) {
+	data, err := get[map[string]HousingData](ctx, c, "/housing/list")
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = make(map[string]HousingData)
+	}
+	return data, nil
 }
 
-func KickPlayer(instance types.Instance, uniqueID string) (*APIResponse, error) {
+func (c *Client) KickPlayer(ctx context.Context, uniqueID string) (string, error) {
 	if uniqueID == "" {
-		return nil, fmt.Errorf("unique_id cannot be empty")
+		return "", fmt.Errorf("unique_id cannot be empty")
 	}
 
 	params := map[string]string{
 		"unique_id": uniqueID,
 	}
-	return makePOSTRequest(instance, "/player/kick", params)
+	return c.post(ctx, "/player/kick", params)
 }
 
-func BanPlayer(instance types.Instance, uniqueID string, hours int, reason string) (*APIResponse, error) {
+func (c *Client) BanPlayer(ctx context.Context, uniqueID string, hours int, reason string) (string, error) {
 	if uniqueID == "" {
-		return nil, fmt.Errorf("unique_id cannot be empty")
+		return "", fmt.Errorf("unique_id cannot be empty")
 	}
 
 	params := map[string]string{
@@ -174,16 +249,35 @@ func BanPlayer(instance types.Instance, uniqueID string, hours int, reason strin
 		params["reason"] = reason
 	}
 
-	return makePOSTRequest(instance, "/player/ban", params)
+	return c.post(ctx, "/player/ban", params)
 }
 
-func UnbanPlayer(instance types.Instance, uniqueID string) (*APIResponse, error) {
+func (c *Client) UnbanPlayer(ctx context.Context, uniqueID string) (string, error) {
 	if uniqueID == "" {
-		return nil, fmt.Errorf("unique_id cannot be empty")
+		return "", fmt.Errorf("unique_id cannot be empty")
 	}
 
 	params := map[string]string{
 		"unique_id": uniqueID,
 	}
-	return makePOSTRequest(instance, "/player/unban", params)
+	return c.post(ctx, "/player/unban", params)
+}
+
+func get[T any](ctx context.Context, c *Client, endpoint string) (T, error) {
+	var data T
+
+	resp, err := c.do(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return data, err
+	}
+
+	return decodeData[T](endpoint, resp)
+}
+
+func (c *Client) post(ctx context.Context, endpoint string, params map[string]string) (string, error) {
+	resp, err := c.do(ctx, http.MethodPost, endpoint, params)
+	if err != nil {
+		return "", err
+	}
+	return resp.Message, nil
 }
@@ -0,0 +1,180 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"motor-town-server-tool/modules/api"
+	"motor-town-server-tool/modules/fakeserver"
+	"motor-town-server-tool/modules/types"
+)
+
+const testPassword = "secret"
+
+var fastRetry = api.RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     time.Millisecond,
+	Multiplier:     1,
+}
+
+// startServer serves fake on an httptest server and counts the requests it
+// receives.
+func startServer(t *testing.T, fake *fakeserver.Server) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fake.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func newClient(t *testing.T, server *httptest.Server, password string, opts ...api.Option) *api.Client {
+	t.Helper()
+
+	client, err := api.NewClient(types.Instance{IP: server.URL, Password: password}, opts...)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client
+}
+
+func TestWrongPasswordIsUnauthorized(t *testing.T) {
+	server, _ := startServer(t, fakeserver.New(testPassword))
+	client := newClient(t, server, "wrong")
+
+	_, err := client.GetPlayerCount(context.Background())
+	if !errors.Is(err, api.ErrUnauthorized) {
+		t.Fatalf("GetPlayerCount error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestKickBanUnban(t *testing.T) {
+	fake := fakeserver.New(testPassword)
+	fake.AddPlayer("Alice", "1001")
+	fake.AddPlayer("Bob", "1002")
+
+	server, _ := startServer(t, fake)
+	client := newClient(t, server, testPassword)
+	ctx := context.Background()
+
+	if _, err := client.KickPlayer(ctx, "1001"); err != nil {
+		t.Fatalf("KickPlayer: %v", err)
+	}
+	players, err := client.GetPlayerList(ctx)
+	if err != nil {
+		t.Fatalf("GetPlayerList: %v", err)
+	}
+	if len(players) != 1 || players[0].UniqueID != "1002" {
+		t.Fatalf("players after kick = %+v, want only Bob", players)
+	}
+
+	if _, err := client.BanPlayer(ctx, "1002", 2, "griefing"); err != nil {
+		t.Fatalf("BanPlayer: %v", err)
+	}
+	if players := fake.Players(); len(players) != 0 {
+		t.Fatalf("players after ban = %+v, want none", players)
+	}
+	bans, err := client.GetBanList(ctx)
+	if err != nil {
+		t.Fatalf("GetBanList: %v", err)
+	}
+	if len(bans) != 1 || bans[0].UniqueID != "1002" || bans[0].Name != "Bob" || bans[0].Reason != "griefing" {
+		t.Fatalf("bans = %+v, want Bob banned for griefing", bans)
+	}
+	expires, ok := bans[0].ExpireTime.Time()
+	if !ok || expires.Before(time.Now().Add(time.Hour)) || expires.After(time.Now().Add(2*time.Hour)) {
+		t.Fatalf("ban expires at %v (%v), want about 2 hours from now", expires, ok)
+	}
+
+	if _, err := client.UnbanPlayer(ctx, "1002"); err != nil {
+		t.Fatalf("UnbanPlayer: %v", err)
+	}
+	if bans := fake.Bans(); len(bans) != 0 {
+		t.Fatalf("bans after unban = %+v, want none", bans)
+	}
+
+	var apiErr *api.APIError
+	if _, err := client.KickPlayer(ctx, "1001"); !errors.As(err, &apiErr) {
+		t.Fatalf("kicking an offline player: error = %v, want *APIError", err)
+	}
+}
+
+func TestServerErrorsAreRetried(t *testing.T) {
+	fake := fakeserver.New(testPassword)
+	fake.InjectFault("/player/count", fakeserver.Fault{StatusCode: http.StatusInternalServerError})
+
+	server, requests := startServer(t, fake)
+	client := newClient(t, server, testPassword, api.WithRetryPolicy(fastRetry))
+
+	_, err := client.GetPlayerCount(context.Background())
+	var httpErr *api.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("GetPlayerCount error = %v, want *HTTPError with status 500", err)
+	}
+	if got := atomic.LoadInt32(requests); got != int32(fastRetry.MaxAttempts) {
+		t.Fatalf("server saw %d requests, want %d", got, fastRetry.MaxAttempts)
+	}
+}
+
+func TestServerErrorRecovers(t *testing.T) {
+	fake := fakeserver.New(testPassword)
+	fake.AddPlayer("Alice", "1001")
+	fake.InjectFault("/player/count", fakeserver.Fault{StatusCode: http.StatusServiceUnavailable, Count: 2})
+
+	server, requests := startServer(t, fake)
+	client := newClient(t, server, testPassword, api.WithRetryPolicy(fastRetry))
+
+	count, err := client.GetPlayerCount(context.Background())
+	if err != nil {
+		t.Fatalf("GetPlayerCount: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("GetPlayerCount = %d, want 1", count)
+	}
+	if got := atomic.LoadInt32(requests); got != 3 {
+		t.Fatalf("server saw %d requests, want 3", got)
+	}
+}
+
+func TestPostIsNotRetried(t *testing.T) {
+	fake := fakeserver.New(testPassword)
+	fake.InjectFault("/player/kick", fakeserver.Fault{StatusCode: http.StatusInternalServerError})
+
+	server, requests := startServer(t, fake)
+	client := newClient(t, server, testPassword, api.WithRetryPolicy(fastRetry))
+
+	var httpErr *api.HTTPError
+	if _, err := client.KickPlayer(context.Background(), "1001"); !errors.As(err, &httpErr) {
+		t.Fatalf("KickPlayer error = %v, want *HTTPError", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Fatalf("server saw %d requests, want 1", got)
+	}
+}
+
+func TestMalformedResponseIsDecodeError(t *testing.T) {
+	fake := fakeserver.New(testPassword)
+	fake.InjectFault("/player/list", fakeserver.Fault{Malformed: true})
+
+	server, requests := startServer(t, fake)
+	client := newClient(t, server, testPassword, api.WithRetryPolicy(fastRetry))
+
+	_, err := client.GetPlayerList(context.Background())
+	var decodeErr *api.DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("GetPlayerList error = %v, want *DecodeError", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Fatalf("server saw %d requests, want 1: decode errors are not retried", got)
+	}
+}
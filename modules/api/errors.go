@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrUnauthorized = errors.New("unauthorized: check the instance password")
+	ErrUnreachable  = errors.New("server unreachable")
+	ErrCertificate  = errors.New("TLS certificate verification failed")
+)
+
+type HTTPError struct {
+	Endpoint   string
+	StatusCode int
+	Message    string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s: HTTP %d %s", e.Endpoint, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%s: HTTP %d: %s", e.Endpoint, e.StatusCode, e.Message)
+}
+
+func (e *HTTPError) Is(target error) bool {
+	if target == ErrUnauthorized {
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+type APIError struct {
+	Endpoint string
+	Message  string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: API call failed: %s", e.Endpoint, e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	if target == ErrUnauthorized {
+		return strings.Contains(strings.ToLower(e.Message), "password")
+	}
+	return false
+}
+
+type DecodeError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%s: failed to decode response: %v", e.Endpoint, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+type UnreachableError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e *UnreachableError) Error() string {
+	return fmt.Sprintf("%s: %v: %v", e.Endpoint, ErrUnreachable, e.Err)
+}
+
+func (e *UnreachableError) Unwrap() error {
+	return e.Err
+}
+
+func (e *UnreachableError) Is(target error) bool {
+	return target == ErrUnreachable
+}
+
+// CertificateError reports a TLS handshake that failed because a
+// certificate was rejected: the server's by us, or ours by the server.
+type CertificateError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e *CertificateError) Error() string {
+	return fmt.Sprintf("%s: %v: %v", e.Endpoint, ErrCertificate, e.Err)
+}
+
+func (e *CertificateError) Unwrap() error {
+	return e.Err
+}
+
+func (e *CertificateError) Is(target error) bool {
+	return target == ErrCertificate
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type APIResponse struct {
+	Data      json.RawMessage `json:"data"`
+	Message   string          `json:"message"`
+	Succeeded bool            `json:"succeeded"`
+}
+
+type VersionData struct {
+	Version string `json:"version"`
+}
+
+type PlayerCountData struct {
+	NumPlayers int `json:"num_players"`
+}
+
+type Player struct {
+	Name     string `json:"name"`
+	UniqueID string `json:"unique_id"`
+
+	// Ban list entries carry these when the server provides them.
+	Reason     string    `json:"reason,omitempty"`
+	ExpireTime Timestamp `json:"expire_time,omitempty"`
+}
+
+// Timestamp is a time as sent by the server: a string in one of several
+// layouts or a number of seconds since the Unix epoch. Values of other
+// types are ignored rather than failing the whole response.
+type Timestamp string
+
+func (t *Timestamp) UnmarshalJSON(raw []byte) error {
+	raw = bytes.TrimSpace(raw)
+	switch {
+	case len(raw) > 0 && raw[0] == '"':
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+		*t = Timestamp(value)
+	case len(raw) > 0 && (raw[0] == '-' || (raw[0] >= '0' && raw[0] <= '9')):
+		// Zero and negative numbers stand for "never".
+		if n, err := strconv.ParseFloat(string(raw), 64); err == nil && n <= 0 {
+			*t = ""
+		} else {
+			*t = Timestamp(raw)
+		}
+	default:
+		*t = ""
+	}
+	return nil
+}
+
+var timestampLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006.01.02-15.04.05",
+}
+
+// Time parses the timestamp, reading times without a zone as UTC. It
+// reports false when the timestamp is empty, zero or not recognised.
+func (t Timestamp) Time() (time.Time, bool) {
+	value := strings.TrimSpace(string(t))
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		switch {
+		case n <= 0:
+			return time.Time{}, false
+		case n > 1e12:
+			return time.UnixMilli(n), true
+		default:
+			return time.Unix(n, 0), true
+		}
+	}
+
+	for _, layout := range timestampLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, true
+		}
+	}
+	return time.Time{}, false
+}
+
+type HousingData struct {
+	OwnerUniqueID string `json:"owner_unique_id"`
+	ExpireTime    string `json:"expire_time"`
+}
+
+// PlayerSet decodes the player collections returned by the web API, which
+// are sent either as an array or as an object keyed by list index.
+type PlayerSet []Player
+
+func (p *PlayerSet) UnmarshalJSON(raw []byte) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		*p = PlayerSet{}
+		return nil
+	}
+
+	if raw[0] == '[' {
+		var players []Player
+		if err := json.Unmarshal(raw, &players); err != nil {
+			return err
+		}
+		*p = players
+		return nil
+	}
+
+	var byKey map[string]Player
+	if err := json.Unmarshal(raw, &byKey); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+
+	players := make(PlayerSet, 0, len(keys))
+	for _, key := range keys {
+		players = append(players, byKey[key])
+	}
+	*p = players
+	return nil
+}
+
+func decodeData[T any](endpoint string, resp *APIResponse) (T, error) {
+	var data T
+
+	if len(resp.Data) == 0 || bytes.Equal(bytes.TrimSpace(resp.Data), []byte("null")) {
+		return data, nil
+	}
+
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		return data, &DecodeError{Endpoint: endpoint, Err: err}
+	}
+
+	return data, nil
+}
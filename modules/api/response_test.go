@@ -0,0 +1,77 @@
+package api_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"motor-town-server-tool/modules/api"
+)
+
+func TestTimestampTime(t *testing.T) {
+	noon := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Time
+		ok    bool
+	}{
+		{value: "2026-10-16T12:00:00Z", want: noon, ok: true},
+		{value: "2026-10-16T14:00:00+02:00", want: noon, ok: true},
+		{value: "2026-10-16T12:00:00", want: noon, ok: true},
+		{value: "2026-10-16 12:00:00", want: noon, ok: true},
+		{value: "2026.10.16-12.00.00", want: noon, ok: true},
+		{value: " 2026-10-16T12:00:00Z ", want: noon, ok: true},
+		{value: "1792152000", want: noon, ok: true},
+		{value: "1792152000000", want: noon, ok: true},
+		{value: "1792152000123", want: noon.Add(123 * time.Millisecond), ok: true},
+		{value: "1000000000000", want: time.Unix(1000000000000, 0), ok: true},
+		{value: "1", want: time.Unix(1, 0), ok: true},
+		{value: ""},
+		{value: "0"},
+		{value: "-1"},
+		{value: "soon"},
+		{value: "2026-10-16"},
+		{value: "1.7918064e9"},
+		{value: "99999999999999999999"},
+	}
+
+	for _, tt := range tests {
+		got, ok := api.Timestamp(tt.value).Time()
+		if ok != tt.ok {
+			t.Errorf("Timestamp(%q).Time() ok = %v, want %v", tt.value, ok, tt.ok)
+			continue
+		}
+		if ok && !got.Equal(tt.want) {
+			t.Errorf("Timestamp(%q).Time() = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTimestampUnmarshal(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want api.Timestamp
+	}{
+		{raw: `"2026-10-16T12:00:00Z"`, want: "2026-10-16T12:00:00Z"},
+		{raw: `1792152000`, want: "1792152000"},
+		{raw: `1792152000000`, want: "1792152000000"},
+		{raw: `0`, want: ""},
+		{raw: `-1`, want: ""},
+		{raw: `null`, want: ""},
+		{raw: `true`, want: ""},
+		{raw: `{"seconds": 5}`, want: ""},
+		{raw: `[]`, want: ""},
+	}
+
+	for _, tt := range tests {
+		var player api.Player
+		if err := json.Unmarshal([]byte(`{"name": "Bob", "unique_id": "1", "expire_time": `+tt.raw+`}`), &player); err != nil {
+			t.Errorf("expire_time %s: %v", tt.raw, err)
+			continue
+		}
+		if player.ExpireTime != tt.want {
+			t.Errorf("expire_time %s decoded as %q, want %q", tt.raw, player.ExpireTime, tt.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package api_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"motor-town-server-tool/modules/api"
+	"motor-town-server-tool/modules/exitcode"
+	"motor-town-server-tool/modules/fakeserver"
+	"motor-town-server-tool/modules/types"
+)
+
+type certificate struct {
+	tls      tls.Certificate
+	certFile string
+	keyFile  string
+}
+
+// newCertificate generates a self-signed certificate for 127.0.0.1 and
+// writes it and its key to PEM files.
+func newCertificate(t *testing.T, name string) certificate {
+	t.Helper()
+
+	cert, certPEM, err := fakeserver.GenerateCertificate([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("GenerateCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+
+	dir := t.TempDir()
+	c := certificate{
+		tls:      cert,
+		certFile: filepath.Join(dir, name+".pem"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	if err := os.WriteFile(c.certFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(c.keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// startTLSServer serves a fake server over TLS with serverCert, requiring
+// a client certificate signed by clientCA when one is given. It counts the
+// connections made to it.
+func startTLSServer(t *testing.T, serverCert certificate, clientCA *certificate) (*httptest.Server, *int32) {
+	t.Helper()
+
+	fake := fakeserver.New(testPassword)
+	fake.AddPlayer("Alice", "1001")
+
+	var connections int32
+	server := httptest.NewUnstartedServer(fake)
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert.tls}}
+	if clientCA != nil {
+		pool := x509.NewCertPool()
+		pool.AddCert(clientCA.tls.Leaf)
+		server.TLS.ClientCAs = pool
+		server.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server, &connections
+}
+
+func TestTLS(t *testing.T) {
+	serverCert := newCertificate(t, "server")
+	clientCert := newCertificate(t, "client")
+	otherCert := newCertificate(t, "other")
+
+	tests := []struct {
+		name     string
+		clientCA *certificate
+		settings types.TLSConfig
+		wantErr  error
+	}{
+		{
+			name:     "ca_file",
+			settings: types.TLSConfig{CAFile: serverCert.certFile},
+		},
+		{
+			name:     "unknown authority",
+			settings: types.TLSConfig{},
+			wantErr:  api.ErrCertificate,
+		},
+		{
+			name:     "wrong ca_file",
+			settings: types.TLSConfig{CAFile: otherCert.certFile},
+			wantErr:  api.ErrCertificate,
+		},
+		{
+			name:     "insecure_skip_verify",
+			settings: types.TLSConfig{InsecureSkipVerify: true},
+		},
+		{
+			name:     "client certificate",
+			clientCA: &clientCert,
+			settings: types.TLSConfig{CAFile: serverCert.certFile, CertFile: clientCert.certFile, KeyFile: clientCert.keyFile},
+		},
+		{
+			name:     "missing client certificate",
+			clientCA: &clientCert,
+			settings: types.TLSConfig{CAFile: serverCert.certFile},
+			wantErr:  api.ErrCertificate,
+		},
+		{
+			name:     "untrusted client certificate",
+			clientCA: &clientCert,
+			settings: types.TLSConfig{CAFile: serverCert.certFile, CertFile: otherCert.certFile, KeyFile: otherCert.keyFile},
+			wantErr:  api.ErrCertificate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, connections := startTLSServer(t, serverCert, tt.clientCA)
+
+			settings := tt.settings
+			client, err := api.NewClient(types.Instance{IP: server.URL, Password: testPassword, TLS: &settings}, api.WithRetryPolicy(fastRetry))
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+
+			count, err := client.GetPlayerCount(context.Background())
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("GetPlayerCount: %v", err)
+				}
+				if count != 1 {
+					t.Fatalf("GetPlayerCount = %d, want 1", count)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetPlayerCount error = %v, want %v", err, tt.wantErr)
+			}
+			if errors.Is(err, api.ErrUnreachable) {
+				t.Fatalf("certificate failure also reported as unreachable: %v", err)
+			}
+			if code := exitcode.FromError(err); code != exitcode.Certificate {
+				t.Fatalf("exit code %d, want %d", code, exitcode.Certificate)
+			}
+			if got := atomic.LoadInt32(connections); got != 1 {
+				t.Fatalf("server saw %d connections, want 1: certificate failures are not retried", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package check
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+
+	"motor-town-server-tool/modules/config"
+	"motor-town-server-tool/modules/exitcode"
+	"motor-town-server-tool/modules/output"
+	"motor-town-server-tool/modules/probe"
+)
+
+type Command struct{}
+
+func (c *Command) Name() string {
+	return "check"
+}
+
+func (c *Command) Description() string {
+	return "Check that instances are reachable and their passwords are accepted"
+}
+
+type checkRow struct {
+	Instance      string `json:"instance" yaml:"instance"`
+	Address       string `json:"address" yaml:"address"`
+	Status        string `json:"status" yaml:"status"`
+	Reachable     bool   `json:"reachable" yaml:"reachable"`
+	Authenticated bool   `json:"authenticated" yaml:"authenticated"`
+	Version       string `json:"version,omitempty" yaml:"version,omitempty"`
+	Players       int    `json:"players" yaml:"players"`
+	LatencyMS     int64  `json:"latency_ms" yaml:"latency_ms"`
+	Error         string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (c *Command) Execute(args []string) error {
+	flags := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	all := flags.Bool("all", false, "check every configured instance")
+	parallel := flags.Int("parallel", 8, "maximum number of instances to check concurrently")
+
+	var selector config.Selector
+	selector.BindFlags(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return exitcode.Usagef("%v", err)
+	}
+
+	rest := flags.Args()
+	if len(rest) == 1 && rest[0] == "all" {
+		*all = true
+		rest = nil
+	}
+	if len(rest) > 1 {
+		return usageError()
+	}
+	if len(rest) == 1 {
+		selector.Names = strings.Split(rest[0], ",")
+	}
+	if *all {
+		selector = config.Selector{}
+	} else if selector.IsEmpty() {
+		return usageError()
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	names, err := cfg.Select(selector)
+	if err != nil {
+		return exitcode.Usagef("%v", err)
+	}
+	if len(names) == 0 {
+		return exitcode.Usagef("no instances match the selection")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	results := checkAll(ctx, cfg, names, *parallel)
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("check aborted: %w", err)
+	}
+
+	rows := make([]checkRow, len(names))
+	csvRows := make([][]string, len(names))
+	errs := make([]error, len(names))
+	failed := 0
+
+	for i, name := range names {
+		instance, _ := cfg.GetInstance(name)
+		result := results[i]
+
+		row := checkRow{
+			Instance:      name,
+			Address:       instance.Address(),
+			Status:        string(result.Status),
+			Reachable:     result.Reachable,
+			Authenticated: result.Authenticated,
+			Version:       result.Version,
+			Players:       result.PlayerCount,
+			LatencyMS:     result.Latency.Milliseconds(),
+		}
+		if result.Err != nil {
+			row.Error = result.Err.Error()
+			errs[i] = result.Err
+			failed++
+		}
+
+		rows[i] = row
+		csvRows[i] = []string{
+			row.Instance, row.Address, row.Status, strconv.FormatBool(row.Reachable), strconv.FormatBool(row.Authenticated),
+			row.Version, strconv.Itoa(row.Players), strconv.FormatInt(row.LatencyMS, 10), row.Error,
+		}
+	}
+
+	err = output.Render(os.Stdout, output.CurrentFormat(), output.Result{
+		Data:   rows,
+		Header: []string{"instance", "address", "status", "reachable", "authenticated", "version", "players", "latency_ms", "error"},
+		Rows:   csvRows,
+		Human: func(w io.Writer) {
+			for i, name := range names {
+				mark := "✓"
+				if !results[i].OK() {
+					mark = "✗"
+				}
+				fmt.Fprintf(w, "%s %s (%s): %s\n", mark, name, rows[i].Address, results[i].Summary())
+			}
+			fmt.Fprintf(w, "\nSummary: %d/%d instances healthy\n", len(names)-failed, len(names))
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if failed == 0 {
+		return nil
+	}
+	return &exitcode.Error{
+		Code: exitcode.Combine(errs),
+		Err:  fmt.Errorf("%d of %d instances failed the check", failed, len(names)),
+	}
+}
+
+func checkAll(ctx context.Context, cfg *config.Config, names []string, parallel int) []probe.Result {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := make([]probe.Result, len(names))
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, name := range names {
+		instance, err := cfg.ResolveInstance(name)
+		if err != nil {
+			results[i] = probe.Result{Status: probe.StatusError, Err: err}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			results[i] = probe.Run(ctx, instance)
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+func usageError() error {
+	return exitcode.Usagef("usage: check [--parallel <n>] (<instance[,instance...]> | all | --all | --tag <tag> | --group <group> | --region <region>)")
+}
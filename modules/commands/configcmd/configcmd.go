@@ -0,0 +1,52 @@
+package configcmd
+
+import (
+	"fmt"
+
+	"motor-town-server-tool/modules/config"
+	"motor-town-server-tool/modules/exitcode"
+)
+
+type Command struct{}
+
+func (c *Command) Name() string {
+	return "config"
+}
+
+func (c *Command) Description() string {
+	return "Inspect and manage the configuration file"
+}
+
+func (c *Command) Execute(args []string) error {
+	if len(args) < 1 {
+		return usageError()
+	}
+
+	switch args[0] {
+	case "path":
+		return showPath()
+	case "export":
+		return exportConfig(args[1:])
+	case "import":
+		return importConfig(args[1:])
+	default:
+		return usageError()
+	}
+}
+
+func showPath() error {
+	location := config.Locate()
+
+	fmt.Println(location.Path)
+	if location.Exists {
+		fmt.Printf("  (from %s)\n", location.Source)
+	} else {
+		fmt.Printf("  (from %s; file does not exist yet and will be created on save)\n", location.Source)
+	}
+
+	return nil
+}
+
+func usageError() error {
+	return exitcode.Usagef("usage: config (path | export [options] [instance...] | import <file> [options])")
+}
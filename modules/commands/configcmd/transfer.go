@@ -0,0 +1,190 @@
+package configcmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"motor-town-server-tool/modules/config"
+	"motor-town-server-tool/modules/exitcode"
+	"motor-town-server-tool/modules/output"
+)
+
+func exportConfig(args []string) error {
+	flags := flag.NewFlagSet("config export", flag.ContinueOnError)
+	passwords := flags.String("passwords", "redact", "how to write inline passwords: redact, encrypt or plain")
+	format := flags.String("format", "", "file format: toml or json (default: from --file extension, else toml)")
+	file := flags.String("file", "", "write to this file instead of standard output")
+
+	var selector config.Selector
+	selector.BindFlags(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return exitcode.Usagef("%v", err)
+	}
+	selector.Names = flags.Args()
+
+	mode, err := config.ParsePasswordMode(*passwords)
+	if err != nil {
+		return exitcode.Usagef("%v", err)
+	}
+	fileFormat, err := config.ParseFileFormat(*format, *file)
+	if err != nil {
+		return exitcode.Usagef("%v", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	names, err := cfg.Select(selector)
+	if err != nil {
+		return exitcode.Usagef("%v", err)
+	}
+	if len(names) == 0 {
+		return exitcode.Usagef("no instances to export")
+	}
+
+	var passphrase string
+	if mode == config.PasswordsEncrypt {
+		passphrase, err = config.ExportPassphrase("Export passphrase: ", true)
+		if err != nil {
+			return err
+		}
+	}
+
+	exported, err := cfg.Export(names, mode, passphrase)
+	if err != nil {
+		return err
+	}
+
+	data, err := config.Marshal(exported, fileFormat)
+	if err != nil {
+		return err
+	}
+
+	if *file == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	perm := os.FileMode(0644)
+	if mode == config.PasswordsPlain {
+		perm = 0600
+	}
+	if err := os.WriteFile(*file, data, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", *file, err)
+	}
+
+	fmt.Printf("Exported %d instance(s) to %s (passwords: %s)\n", len(names), *file, mode)
+	return nil
+}
+
+type importRow struct {
+	Instance string `json:"instance" yaml:"instance"`
+	Action   string `json:"action" yaml:"action"`
+	SavedAs  string `json:"saved_as,omitempty" yaml:"saved_as,omitempty"`
+	Warning  string `json:"warning,omitempty" yaml:"warning,omitempty"`
+}
+
+func importConfig(args []string) error {
+	flags := flag.NewFlagSet("config import", flag.ContinueOnError)
+	strategy := flags.String("strategy", "skip", "what to do with instances that already exist: skip, overwrite or rename")
+	format := flags.String("format", "", "file format: toml or json (default: from the file extension)")
+	dryRun := flags.Bool("dry-run", false, "show what would change without saving")
+
+	var path string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		path, args = args[0], args[1:]
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitcode.Usagef("%v", err)
+	}
+	rest := flags.Args()
+	if path == "" && len(rest) > 0 {
+		path, rest = rest[0], rest[1:]
+	}
+	if path == "" || len(rest) > 0 {
+		return exitcode.Usagef("usage: config import <file> [--strategy skip|overwrite|rename] [--format toml|json] [--dry-run]")
+	}
+
+	conflicts, err := config.ParseStrategy(*strategy)
+	if err != nil {
+		return exitcode.Usagef("%v", err)
+	}
+	fileFormat, err := config.ParseFileFormat(*format, path)
+	if err != nil {
+		return exitcode.Usagef("%v", err)
+	}
+
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	src, err := config.ReadImport(path, fileFormat, func() (string, error) {
+		return config.ExportPassphrase(fmt.Sprintf("Passphrase for %s: ", path), false)
+	})
+	if err != nil {
+		return err
+	}
+
+	results := cfg.Import(src, conflicts)
+
+	imported := 0
+	for _, result := range results {
+		if result.SavedAs != "" {
+			imported++
+		}
+	}
+
+	if !*dryRun && imported > 0 {
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+	}
+
+	rows := make([]importRow, 0, len(results))
+	csvRows := make([][]string, 0, len(results))
+	for _, result := range results {
+		row := importRow{Instance: result.Name, Action: result.Action, SavedAs: result.SavedAs, Warning: result.Warning}
+		rows = append(rows, row)
+		csvRows = append(csvRows, []string{row.Instance, row.Action, row.SavedAs, row.Warning})
+	}
+
+	return output.Render(os.Stdout, output.CurrentFormat(), output.Result{
+		Data:   rows,
+		Header: []string{"instance", "action", "saved_as", "warning"},
+		Rows:   csvRows,
+		Human: func(w io.Writer) {
+			for _, row := range rows {
+				switch row.Action {
+				case "renamed":
+					fmt.Fprintf(w, "  + %s -> %s (renamed)\n", row.Instance, row.SavedAs)
+				case "skipped":
+					fmt.Fprintf(w, "  - %s (skipped, already exists)\n", row.Instance)
+				default:
+					fmt.Fprintf(w, "  + %s (%s)\n", row.Instance, row.Action)
+				}
+				if row.Warning != "" {
+					fmt.Fprintf(w, "    Warning: %s\n", row.Warning)
+				}
+			}
+
+			if *dryRun {
+				fmt.Fprintf(w, "Dry run: %d of %d instance(s) would be imported from %s.\n", imported, len(rows), path)
+			} else {
+				fmt.Fprintf(w, "Imported %d of %d instance(s) from %s.\n", imported, len(rows), path)
+			}
+		},
+	})
+}
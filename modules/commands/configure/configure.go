@@ -2,16 +2,20 @@ package configure
 
 import (
 	"bufio"
+	"context"
 	"fmt"
-	"net"
+	"io"
 	"os"
-	"regexp"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 
 	"motor-town-server-tool/modules/config"
+	"motor-town-server-tool/modules/probe"
 	"motor-town-server-tool/modules/types"
+
+	"golang.org/x/term"
 )
 
 type Command struct{}
@@ -25,6 +29,16 @@ func (c *Command) Description() string {
 }
 
 func (c *Command) Execute(args []string) error {
+	if len(args) > 0 {
+		return runSubcommand(args)
+	}
+
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
@@ -48,7 +62,7 @@ func (c *Command) Execute(args []string) error {
 			}
 		case 2:
 			if len(cfg.ListInstances()) == 0 {
-				fmt.Println("No instances available to edit.\n")
+				fmt.Print("No instances available to edit.\n\n")
 				continue
 			}
 			if err := editInstance(scanner, cfg); err != nil {
@@ -57,18 +71,38 @@ func (c *Command) Execute(args []string) error {
 			}
 		case 3:
 			if len(cfg.ListInstances()) == 0 {
-				fmt.Println("No instances available to delete.\n")
+				fmt.Print("No instances available to delete.\n\n")
 				continue
 			}
 			if err := deleteInstance(scanner, cfg); err != nil {
 				fmt.Printf("Error deleting instance: %v\n\n", err)
 				continue
 			}
+		case 4:
+			if len(cfg.ListInstances()) == 0 || cfg.EncryptionEnabled() {
+				fmt.Print("Invalid choice. Please try again.\n\n")
+				continue
+			}
+			if err := encryptPasswords(scanner, cfg); err != nil {
+				fmt.Printf("Error encrypting passwords: %v\n\n", err)
+				continue
+			}
+		case 5:
+			restored, err := restoreBackup(scanner)
+			if err != nil {
+				fmt.Printf("Error restoring backup: %v\n\n", err)
+				continue
+			}
+			if restored != nil {
+				cfg = restored
+			}
+			fmt.Println()
+			continue
 		case 0:
 			fmt.Println("Goodbye!")
 			return nil
 		default:
-			fmt.Println("Invalid choice. Please try again.\n")
+			fmt.Print("Invalid choice. Please try again.\n\n")
 			continue
 		}
 
@@ -83,18 +117,15 @@ func (c *Command) Execute(args []string) error {
 func promptInstanceConfig(scanner *bufio.Scanner) (types.Instance, error) {
 	var instance types.Instance
 
-	ip, err := promptWithRetry(scanner, "Enter server IP: ", validateIP)
+	address, err := promptWithRetry(scanner, "Enter server address (IP, hostname or URL): ", config.ValidateAddress)
 	if err != nil {
 		return instance, err
 	}
-	instance.IP = ip
+	instance.IP = address
 
-	portStr, err := promptWithRetry(scanner, "Enter server port: ", validatePort)
-	if err != nil {
+	if err := promptPort(scanner, &instance); err != nil {
 		return instance, err
 	}
-	port, _ := strconv.Atoi(portStr)
-	instance.Port = port
 
 	password, err := promptWithRetry(scanner, "Enter server password: ", validatePassword)
 	if err != nil {
@@ -102,9 +133,58 @@ func promptInstanceConfig(scanner *bufio.Scanner) (types.Instance, error) {
 	}
 	instance.Password = password
 
+	if err := promptInstanceLabels(scanner, &instance); err != nil {
+		return instance, err
+	}
+
 	return instance, nil
 }
 
+func promptInstanceLabels(scanner *bufio.Scanner, instance *types.Instance) error {
+	description, err := promptOptional(scanner, "Enter description", instance.Description, validateDescription)
+	if err != nil {
+		return err
+	}
+	instance.Description = description
+
+	group, err := promptOptional(scanner, "Enter group", instance.Group, validateLabel)
+	if err != nil {
+		return err
+	}
+	instance.Group = group
+
+	region, err := promptOptional(scanner, "Enter region", instance.Region, validateLabel)
+	if err != nil {
+		return err
+	}
+	instance.Region = region
+
+	tags, err := promptOptional(scanner, "Enter tags (comma-separated)", strings.Join(instance.Tags, ","), validateTags)
+	if err != nil {
+		return err
+	}
+	instance.Tags = parseTags(tags)
+
+	return nil
+}
+
+func promptOptional(scanner *bufio.Scanner, prompt, defaultValue string, validator func(string) error) (string, error) {
+	if defaultValue == "" {
+		return promptWithRetry(scanner, prompt+" (optional): ", validator)
+	}
+
+	value, err := promptWithDefaults(scanner, prompt+" ('-' to clear)", defaultValue, func(input string) error {
+		if input == "-" {
+			return nil
+		}
+		return validator(input)
+	})
+	if value == "-" {
+		value = ""
+	}
+	return value, err
+}
+
 func promptWithRetry(scanner *bufio.Scanner, prompt string, validator func(string) error) (string, error) {
 	const maxAttempts = 3
 
@@ -131,81 +211,104 @@ func promptWithRetry(scanner *bufio.Scanner, prompt string, validator func(strin
 	return "", fmt.Errorf("unexpected error in retry loop")
 }
 
-func validateInstanceName(name string) error {
-	if name == "" {
-		return fmt.Errorf("instance name cannot be empty")
-	}
-	if len(name) > 72 {
-		return fmt.Errorf("instance name cannot exceed 72 characters")
+func promptPort(scanner *bufio.Scanner, instance *types.Instance) error {
+	if instance.IsURL() {
+		instance.Port = 0
+		return nil
 	}
 
-	validName := regexp.MustCompile(`^[a-z0-9_-]+$`)
-	if !validName.MatchString(name) {
-		return fmt.Errorf("instance name can only contain lowercase letters (a-z), numbers (0-9), hyphens (-), and underscores (_)")
+	var portStr string
+	var err error
+	if instance.Port == 0 {
+		portStr, err = promptWithRetry(scanner, "Enter server port: ", config.ValidatePort)
+	} else {
+		portStr, err = promptWithDefaults(scanner, "Enter server port", strconv.Itoa(instance.Port), config.ValidatePort)
+	}
+	if err != nil {
+		return err
 	}
 
+	instance.Port, _ = strconv.Atoi(portStr)
 	return nil
 }
 
-func validateIP(ip string) error {
-	if ip == "" {
-		return fmt.Errorf("IP cannot be empty")
+func validatePassword(password string) error {
+	if password == "" {
+		return fmt.Errorf("password cannot be empty")
 	}
-
-	parsedIP := net.ParseIP(ip)
-	if parsedIP == nil {
-		return fmt.Errorf("invalid IP address format")
+	if len(password) > 72 {
+		return fmt.Errorf("password cannot exceed 72 characters")
 	}
 
-	ipv4 := parsedIP.To4()
-	if ipv4 == nil {
-		return fmt.Errorf("only IPv4 addresses are supported")
+	return nil
+}
+
+func validateDescription(description string) error {
+	if len(description) > 200 {
+		return fmt.Errorf("description cannot exceed 200 characters")
 	}
 
-	parts := strings.Split(ip, ".")
-	if len(parts) != 4 {
-		return fmt.Errorf("IP address must have exactly 4 parts separated by dots")
+	return nil
+}
+
+func validateLabel(label string) error {
+	if label == "" {
+		return nil
 	}
+	return config.ValidateInstanceName(label)
+}
 
-	for i, part := range parts {
-		num, err := strconv.Atoi(part)
-		if err != nil {
-			return fmt.Errorf("IP part %d is not a valid number: %s", i+1, part)
-		}
-		if num < 0 || num > 255 {
-			return fmt.Errorf("IP part %d must be between 0 and 255, got: %d", i+1, num)
+func validateTags(tags string) error {
+	for _, tag := range parseTags(tags) {
+		if err := config.ValidateInstanceName(tag); err != nil {
+			return fmt.Errorf("invalid tag '%s': %v", tag, err)
 		}
 	}
 
 	return nil
 }
 
-func validatePort(portStr string) error {
-	if portStr == "" {
-		return fmt.Errorf("port cannot be empty")
+func parseTags(tags string) []string {
+	var parsed []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		parsed = append(parsed, tag)
 	}
+	return parsed
+}
 
-	port, err := strconv.Atoi(portStr)
-	if err != nil {
-		return fmt.Errorf("port must be a number: %s", portStr)
+func displayPassword(cfg *config.Config, instance types.Instance) string {
+	if source := instance.PasswordSource(); source != "" {
+		return fmt.Sprintf("(from %s)", source)
 	}
-
-	if port < 0 || port > 65535 {
-		return fmt.Errorf("port must be between 0 and 65535, got: %d", port)
+	if cfg.EncryptionEnabled() {
+		return "(encrypted)"
 	}
-
-	return nil
+	return maskPassword(instance.Password)
 }
 
-func validatePassword(password string) error {
-	if password == "" {
-		return fmt.Errorf("password cannot be empty")
-	}
-	if len(password) > 72 {
-		return fmt.Errorf("password cannot exceed 72 characters")
+// passwordStatus says how an instance's password is provided without
+// revealing any of it, for structured output read by scripts.
+func passwordStatus(cfg *config.Config, instance types.Instance) string {
+	switch {
+	case instance.PasswordEnv != "":
+		return "env:" + instance.PasswordEnv
+	case instance.PasswordFile != "":
+		return "file:" + instance.PasswordFile
+	case instance.PasswordCommand != "":
+		return "command"
+	case instance.Password == "":
+		return "none"
+	case cfg.EncryptionEnabled():
+		return "encrypted"
+	default:
+		return "set"
 	}
-
-	return nil
 }
 
 func maskPassword(password string) string {
@@ -225,7 +328,7 @@ func showMenu(cfg *config.Config) {
 		sort.Strings(instances)
 		for _, name := range instances {
 			instance, _ := cfg.GetInstance(name)
-			fmt.Printf("  - %s (%s:%d)\n", name, instance.IP, instance.Port)
+			fmt.Printf("  - %s (%s)%s\n", name, instance.Address(), instance.Labels())
 		}
 		fmt.Println()
 	}
@@ -234,6 +337,12 @@ func showMenu(cfg *config.Config) {
 	if len(instances) > 0 {
 		fmt.Println("[2] Edit Instance")
 		fmt.Println("[3] Delete Instance")
+		if !cfg.EncryptionEnabled() {
+			fmt.Println("[4] Encrypt Passwords")
+		}
+	}
+	if backups, _ := config.ListBackups(); len(backups) > 0 {
+		fmt.Println("[5] Restore Backup")
 	}
 	fmt.Println("[0] Exit")
 	fmt.Println()
@@ -257,7 +366,7 @@ func promptChoice(scanner *bufio.Scanner) (int, error) {
 func addInstance(scanner *bufio.Scanner, cfg *config.Config) error {
 	fmt.Println("\n=== Add New Instance ===")
 
-	instanceName, err := promptWithRetry(scanner, "Enter instance name: ", validateInstanceName)
+	instanceName, err := promptWithRetry(scanner, "Enter instance name: ", config.ValidateInstanceName)
 	if err != nil {
 		return err
 	}
@@ -271,12 +380,17 @@ func addInstance(scanner *bufio.Scanner, cfg *config.Config) error {
 		return err
 	}
 
+	instance, err = verifyInstance(scanner, instance)
+	if err != nil {
+		return err
+	}
+
 	cfg.AddInstance(instanceName, instance)
 
 	fmt.Printf("Instance '%s' added successfully!\n", instanceName)
-	fmt.Printf("  IP: %s\n", instance.IP)
-	fmt.Printf("  Port: %d\n", instance.Port)
-	fmt.Printf("  Password: %s\n", maskPassword(instance.Password))
+	fmt.Printf("  Address: %s\n", instance.Address())
+	fmt.Printf("  Password: %s\n", displayPassword(cfg, instance))
+	printInstanceLabels(os.Stdout, instance)
 
 	return nil
 }
@@ -290,7 +404,7 @@ func editInstance(scanner *bufio.Scanner, cfg *config.Config) error {
 	fmt.Println("Available instances:")
 	for i, name := range instances {
 		instance, _ := cfg.GetInstance(name)
-		fmt.Printf("[%d] %s (%s:%d)\n", i+1, name, instance.IP, instance.Port)
+		fmt.Printf("[%d] %s (%s)%s\n", i+1, name, instance.Address(), instance.Labels())
 	}
 	fmt.Println()
 
@@ -309,7 +423,7 @@ func editInstance(scanner *bufio.Scanner, cfg *config.Config) error {
 	existing, _ := cfg.GetInstance(instanceName)
 
 	fmt.Printf("\nEditing instance '%s'\n", instanceName)
-	fmt.Printf("Current: %s:%d\n", existing.IP, existing.Port)
+	fmt.Printf("Current: %s\n", existing.Address())
 	fmt.Println("Enter new values (press Enter to keep current value):")
 
 	newInstance, err := promptInstanceConfigWithDefaults(scanner, existing)
@@ -317,16 +431,86 @@ func editInstance(scanner *bufio.Scanner, cfg *config.Config) error {
 		return err
 	}
 
+	newInstance, err = verifyInstance(scanner, newInstance)
+	if err != nil {
+		return err
+	}
+
 	cfg.AddInstance(instanceName, newInstance)
 
 	fmt.Printf("Instance '%s' updated successfully!\n", instanceName)
-	fmt.Printf("  IP: %s\n", newInstance.IP)
-	fmt.Printf("  Port: %d\n", newInstance.Port)
-	fmt.Printf("  Password: %s\n", maskPassword(newInstance.Password))
+	fmt.Printf("  Address: %s\n", newInstance.Address())
+	fmt.Printf("  Password: %s\n", displayPassword(cfg, newInstance))
+	printInstanceLabels(os.Stdout, newInstance)
 
 	return nil
 }
 
+// verifyInstance offers to probe instance before it is saved and, if the
+// check fails, lets the user save it anyway or re-enter its values.
+func verifyInstance(scanner *bufio.Scanner, instance types.Instance) (types.Instance, error) {
+	fmt.Print("Test the connection before saving? (Y/n): ")
+	if !scanner.Scan() {
+		return instance, fmt.Errorf("failed to read input")
+	}
+
+	answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+	if answer == "n" || answer == "no" {
+		return instance, nil
+	}
+
+	for {
+		fmt.Printf("Testing connection to %s...\n", instance.Address())
+		result := probeInstance(context.Background(), instance)
+		if result.OK() {
+			fmt.Printf("✓ %s\n", result.Summary())
+			return instance, nil
+		}
+
+		fmt.Printf("✗ %s\n", result.Summary())
+		fmt.Println("[1] Save anyway")
+		fmt.Println("[2] Re-enter values")
+		fmt.Println("[0] Cancel")
+
+		choice, err := promptChoice(scanner)
+		if err != nil {
+			return instance, err
+		}
+
+		switch choice {
+		case 1:
+			return instance, nil
+		case 2:
+			fmt.Println("Enter new values (press Enter to keep the value just entered):")
+			instance, err = promptInstanceConfigWithDefaults(scanner, instance)
+			if err != nil {
+				return instance, err
+			}
+		case 0:
+			return instance, fmt.Errorf("cancelled, instance not saved")
+		default:
+			return instance, fmt.Errorf("invalid choice: %d", choice)
+		}
+	}
+}
+
+func probeInstance(ctx context.Context, instance types.Instance) probe.Result {
+	if instance.PasswordSource() != "" {
+		password, err := config.ResolvePassword(instance)
+		if err != nil {
+			return probe.Result{Status: probe.StatusError, Err: err}
+		}
+		instance.Password = password
+	}
+
+	instance, err := config.ResolveTLSFiles(instance)
+	if err != nil {
+		return probe.Result{Status: probe.StatusError, Err: err}
+	}
+
+	return probe.Run(ctx, instance)
+}
+
 func deleteInstance(scanner *bufio.Scanner, cfg *config.Config) error {
 	fmt.Println("\n=== Delete Instance ===")
 
@@ -336,7 +520,7 @@ func deleteInstance(scanner *bufio.Scanner, cfg *config.Config) error {
 	fmt.Println("Available instances:")
 	for i, name := range instances {
 		instance, _ := cfg.GetInstance(name)
-		fmt.Printf("[%d] %s (%s:%d)\n", i+1, name, instance.IP, instance.Port)
+		fmt.Printf("[%d] %s (%s)%s\n", i+1, name, instance.Address(), instance.Labels())
 	}
 	fmt.Println()
 
@@ -373,36 +557,64 @@ func deleteInstance(scanner *bufio.Scanner, cfg *config.Config) error {
 	return nil
 }
 
+func printInstanceLabels(w io.Writer, instance types.Instance) {
+	if instance.Description != "" {
+		fmt.Fprintf(w, "  Description: %s\n", instance.Description)
+	}
+	if instance.Group != "" {
+		fmt.Fprintf(w, "  Group: %s\n", instance.Group)
+	}
+	if instance.Region != "" {
+		fmt.Fprintf(w, "  Region: %s\n", instance.Region)
+	}
+	if len(instance.Tags) > 0 {
+		fmt.Fprintf(w, "  Tags: %s\n", strings.Join(instance.Tags, ", "))
+	}
+}
+
 func promptInstanceConfigWithDefaults(scanner *bufio.Scanner, existing types.Instance) (types.Instance, error) {
-	var instance types.Instance
+	instance := existing
 
-	ip, err := promptWithDefaults(scanner, "Enter server IP", existing.IP, validateIP)
+	address, err := promptWithDefaults(scanner, "Enter server address (IP, hostname or URL)", existing.IP, config.ValidateAddress)
 	if err != nil {
 		return instance, err
 	}
-	instance.IP = ip
+	instance.IP = address
 
-	portStr, err := promptWithDefaults(scanner, "Enter server port", strconv.Itoa(existing.Port), validatePort)
-	if err != nil {
+	if err := promptPort(scanner, &instance); err != nil {
 		return instance, err
 	}
-	port, _ := strconv.Atoi(portStr)
-	instance.Port = port
 
-	password, err := promptWithDefaults(scanner, "Enter server password", existing.Password, validatePassword)
-	if err != nil {
+	if source := existing.PasswordSource(); source != "" {
+		fmt.Printf("Password is read from %s; edit instances.toml to change it.\n", source)
+	} else {
+		password, err := promptWithHiddenDefault(scanner, "Enter server password", existing.Password, validatePassword)
+		if err != nil {
+			return instance, err
+		}
+		instance.Password = password
+	}
+
+	if err := promptInstanceLabels(scanner, &instance); err != nil {
 		return instance, err
 	}
-	instance.Password = password
 
 	return instance, nil
 }
 
 func promptWithDefaults(scanner *bufio.Scanner, prompt, defaultValue string, validator func(string) error) (string, error) {
+	return promptWithDisplayedDefault(scanner, prompt, defaultValue, defaultValue, validator)
+}
+
+func promptWithHiddenDefault(scanner *bufio.Scanner, prompt, defaultValue string, validator func(string) error) (string, error) {
+	return promptWithDisplayedDefault(scanner, prompt, defaultValue, "keep current", validator)
+}
+
+func promptWithDisplayedDefault(scanner *bufio.Scanner, prompt, defaultValue, displayValue string, validator func(string) error) (string, error) {
 	const maxAttempts = 3
 
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		fmt.Printf("%s [%s]: ", prompt, defaultValue)
+		fmt.Printf("%s [%s]: ", prompt, displayValue)
 		if !scanner.Scan() {
 			return "", fmt.Errorf("failed to read input")
 		}
@@ -427,3 +639,128 @@ func promptWithDefaults(scanner *bufio.Scanner, prompt, defaultValue string, val
 
 	return "", fmt.Errorf("unexpected error in retry loop")
 }
+
+func encryptPasswords(scanner *bufio.Scanner, cfg *config.Config) error {
+	fmt.Println("\n=== Encrypt Passwords ===")
+	fmt.Println("Passwords will be encrypted at rest and decrypted when the configuration is loaded.")
+	fmt.Println("[1] Use a master passphrase")
+	fmt.Println("[2] Use a key file")
+	fmt.Println()
+
+	choice, err := promptChoice(scanner)
+	if err != nil {
+		return err
+	}
+
+	switch choice {
+	case 1:
+		passphrase, err := promptSecret(scanner, "Enter master passphrase: ")
+		if err != nil {
+			return err
+		}
+		confirmation, err := promptSecret(scanner, "Confirm master passphrase: ")
+		if err != nil {
+			return err
+		}
+		if passphrase != confirmation {
+			return fmt.Errorf("passphrases do not match")
+		}
+		if err := cfg.EnablePassphraseEncryption(passphrase); err != nil {
+			return err
+		}
+		fmt.Printf("Set %s or enter the passphrase when prompted to unlock the configuration.\n", config.PassphraseEnv)
+	case 2:
+		defaultPath := filepath.Join(filepath.Dir(config.Path()), "mtst.key")
+		path, err := promptWithDefaults(scanner, "Enter key file path (created if missing)", defaultPath, func(input string) error {
+			if input == "" {
+				return fmt.Errorf("key file path cannot be empty")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := cfg.EnableKeyFileEncryption(path); err != nil {
+			return err
+		}
+		fmt.Printf("Keep %s safe; it is required to read the configuration.\n", path)
+	default:
+		return fmt.Errorf("invalid choice: %d", choice)
+	}
+
+	fmt.Printf("Passwords for %d instance(s) will be encrypted.\n", len(cfg.ListInstances()))
+	return nil
+}
+
+func promptSecret(scanner *bufio.Scanner, prompt string) (string, error) {
+	fmt.Print(prompt)
+
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("failed to read input: %w", err)
+		}
+		return string(secret), nil
+	}
+
+	if !scanner.Scan() {
+		return "", fmt.Errorf("failed to read input")
+	}
+	return strings.TrimSpace(scanner.Text()), nil
+}
+
+func restoreBackup(scanner *bufio.Scanner) (*config.Config, error) {
+	fmt.Println("\n=== Restore Backup ===")
+
+	backups, err := config.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		fmt.Println("No backups available.")
+		return nil, nil
+	}
+
+	fmt.Println("Available backups (newest first):")
+	for i, backup := range backups {
+		fmt.Printf("[%d] %s (saved %s)\n", i+1, filepath.Base(backup.Path), backup.ModTime.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Println()
+
+	fmt.Print("Enter backup number to restore: ")
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("failed to read input")
+	}
+
+	choiceStr := strings.TrimSpace(scanner.Text())
+	choice, err := strconv.Atoi(choiceStr)
+	if err != nil || choice < 1 || choice > len(backups) {
+		return nil, fmt.Errorf("invalid choice: %s", choiceStr)
+	}
+
+	backup := backups[choice-1]
+
+	fmt.Printf("Replace the current configuration with %s? The current file is kept as a backup. (y/N): ", filepath.Base(backup.Path))
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("failed to read confirmation")
+	}
+
+	confirmation := strings.ToLower(strings.TrimSpace(scanner.Text()))
+	if confirmation != "y" && confirmation != "yes" {
+		fmt.Println("Restore cancelled.")
+		return nil, nil
+	}
+
+	if err := config.RestoreBackup(backup); err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("backup restored but could not be loaded: %w", err)
+	}
+
+	fmt.Printf("Configuration restored from %s.\n", filepath.Base(backup.Path))
+	return cfg, nil
+}
@@ -0,0 +1,555 @@
+package configure
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"motor-town-server-tool/modules/config"
+	"motor-town-server-tool/modules/exitcode"
+	"motor-town-server-tool/modules/output"
+	"motor-town-server-tool/modules/types"
+)
+
+const subcommandUsage = "usage: configure [add|edit|remove|list|show] ..."
+
+func runSubcommand(args []string) error {
+	switch args[0] {
+	case "add":
+		return addSubcommand(args[1:])
+	case "edit":
+		return editSubcommand(args[1:])
+	case "remove":
+		return removeSubcommand(args[1:])
+	case "list":
+		return listSubcommand(args[1:])
+	case "show":
+		return showSubcommand(args[1:])
+	default:
+		return exitcode.Usagef("unknown subcommand '%s' (%s)", args[0], subcommandUsage)
+	}
+}
+
+type instanceFlags struct {
+	flags      *flag.FlagSet
+	subcommand string
+
+	ip              string
+	port            int
+	scheme          string
+	password        string
+	passwordStdin   bool
+	passwordEnv     string
+	passwordFile    string
+	passwordCommand string
+	description     string
+	group           string
+	region          string
+	tags            string
+	caFile          string
+	certFile        string
+	keyFile         string
+	insecure        bool
+	check           bool
+}
+
+func newInstanceFlags(name string) *instanceFlags {
+	f := &instanceFlags{
+		flags:      flag.NewFlagSet("configure "+name, flag.ContinueOnError),
+		subcommand: name,
+	}
+
+	f.flags.StringVar(&f.ip, "ip", "", "server address: IP, hostname or http(s):// base URL")
+	f.flags.IntVar(&f.port, "port", 0, "web API port (ignored for base URLs)")
+	f.flags.StringVar(&f.scheme, "scheme", "", "http or https")
+	f.flags.StringVar(&f.password, "password", "", "web API password (visible to other local users; prefer the options below)")
+	f.flags.BoolVar(&f.passwordStdin, "password-stdin", false, "read the web API password from the first line of standard input")
+	f.flags.StringVar(&f.passwordEnv, "password-env", "", "environment variable holding the password")
+	f.flags.StringVar(&f.passwordFile, "password-file", "", "file holding the password")
+	f.flags.StringVar(&f.passwordCommand, "password-command", "", "command printing the password")
+	f.flags.StringVar(&f.description, "description", "", "free-text description")
+	f.flags.StringVar(&f.group, "group", "", "group name")
+	f.flags.StringVar(&f.region, "region", "", "region name")
+	f.flags.StringVar(&f.tags, "tags", "", "comma-separated tags")
+	f.flags.StringVar(&f.caFile, "ca-file", "", "CA bundle used to verify the server certificate")
+	f.flags.StringVar(&f.certFile, "cert-file", "", "client certificate for mutual TLS")
+	f.flags.StringVar(&f.keyFile, "key-file", "", "private key for --cert-file")
+	f.flags.BoolVar(&f.insecure, "insecure-skip-verify", false, "skip server certificate verification")
+	f.flags.BoolVar(&f.check, "check", false, "test the connection and only save if it succeeds")
+
+	return f
+}
+
+// parse accepts the instance name before or after the flags.
+func (f *instanceFlags) parse(args []string) (string, error) {
+	name, err := parseNamed(f.flags, f.subcommand, args)
+	if err != nil {
+		return "", err
+	}
+	if err := config.ValidateInstanceName(name); err != nil {
+		return "", exitcode.Usagef("%v", err)
+	}
+	return name, nil
+}
+
+func parseNamed(flags *flag.FlagSet, subcommand string, args []string) (string, error) {
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return "", exitcode.Usagef("%v", err)
+	}
+
+	rest := flags.Args()
+	if name == "" && len(rest) > 0 {
+		name, rest = rest[0], rest[1:]
+	}
+	if name == "" {
+		return "", exitcode.Usagef("usage: configure %s <name> [options]", subcommand)
+	}
+	if len(rest) > 0 {
+		return "", exitcode.Usagef("unexpected argument '%s'", rest[0])
+	}
+
+	return name, nil
+}
+
+// apply validates and copies every flag given on the command line onto
+// instance, leaving the other fields untouched.
+func (f *instanceFlags) apply(instance *types.Instance) error {
+	set := make(map[string]bool)
+	f.flags.Visit(func(fl *flag.Flag) {
+		set[fl.Name] = true
+	})
+
+	sources := 0
+	for _, name := range []string{"password", "password-stdin", "password-env", "password-file", "password-command"} {
+		if set[name] {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return exitcode.Usagef("only one of --password, --password-stdin, --password-env, --password-file or --password-command may be given")
+	}
+
+	if set["ip"] {
+		if err := config.ValidateAddress(f.ip); err != nil {
+			return exitcode.Usagef("invalid --ip: %v", err)
+		}
+		instance.IP = f.ip
+	}
+
+	if set["port"] {
+		if err := config.ValidatePort(strconv.Itoa(f.port)); err != nil {
+			return exitcode.Usagef("invalid --port: %v", err)
+		}
+		instance.Port = f.port
+	}
+	if instance.IsURL() {
+		instance.Port = 0
+	}
+
+	if set["scheme"] {
+		switch strings.ToLower(f.scheme) {
+		case "", "http", "https":
+			instance.Scheme = strings.ToLower(f.scheme)
+		default:
+			return exitcode.Usagef("invalid --scheme: must be http or https")
+		}
+	}
+
+	if set["password-stdin"] {
+		password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read password from stdin: %w", err)
+		}
+		f.password = strings.TrimRight(password, "\r\n")
+		set["password"] = true
+	}
+
+	switch {
+	case set["password"]:
+		if err := validatePassword(f.password); err != nil {
+			return exitcode.Usagef("invalid password: %v", err)
+		}
+		setPasswordSource(instance, f.password, "", "", "")
+	case set["password-env"]:
+		if f.passwordEnv == "" {
+			return exitcode.Usagef("--password-env cannot be empty")
+		}
+		setPasswordSource(instance, "", f.passwordEnv, "", "")
+	case set["password-file"]:
+		if f.passwordFile == "" {
+			return exitcode.Usagef("--password-file cannot be empty")
+		}
+		setPasswordSource(instance, "", "", absPath(f.passwordFile), "")
+	case set["password-command"]:
+		if f.passwordCommand == "" {
+			return exitcode.Usagef("--password-command cannot be empty")
+		}
+		setPasswordSource(instance, "", "", "", f.passwordCommand)
+	}
+
+	if set["description"] {
+		if err := validateDescription(f.description); err != nil {
+			return exitcode.Usagef("invalid --description: %v", err)
+		}
+		instance.Description = f.description
+	}
+	if set["group"] {
+		if err := validateLabel(f.group); err != nil {
+			return exitcode.Usagef("invalid --group: %v", err)
+		}
+		instance.Group = f.group
+	}
+	if set["region"] {
+		if err := validateLabel(f.region); err != nil {
+			return exitcode.Usagef("invalid --region: %v", err)
+		}
+		instance.Region = f.region
+	}
+	if set["tags"] {
+		if err := validateTags(f.tags); err != nil {
+			return exitcode.Usagef("invalid --tags: %v", err)
+		}
+		instance.Tags = parseTags(f.tags)
+	}
+
+	if set["ca-file"] || set["cert-file"] || set["key-file"] || set["insecure-skip-verify"] {
+		tls := types.TLSConfig{}
+		if instance.TLS != nil {
+			tls = *instance.TLS
+		}
+		if set["ca-file"] {
+			tls.CAFile = absPath(f.caFile)
+		}
+		if set["cert-file"] {
+			tls.CertFile = absPath(f.certFile)
+		}
+		if set["key-file"] {
+			tls.KeyFile = absPath(f.keyFile)
+		}
+		if set["insecure-skip-verify"] {
+			tls.InsecureSkipVerify = f.insecure
+		}
+
+		if tls == (types.TLSConfig{}) {
+			instance.TLS = nil
+		} else {
+			instance.TLS = &tls
+		}
+	}
+
+	return nil
+}
+
+func (f *instanceFlags) verify(instance types.Instance) error {
+	if !f.check {
+		return nil
+	}
+
+	result := probeInstance(context.Background(), instance)
+	if !result.OK() {
+		return &exitcode.Error{
+			Code: exitcode.FromError(result.Err),
+			Err:  fmt.Errorf("connection check failed, instance not saved: %s", result.Summary()),
+		}
+	}
+	return nil
+}
+
+// absPath makes a relative path from the command line absolute. The
+// configuration reads relative paths from its own directory, which is not
+// necessarily the one the path was typed in.
+func absPath(path string) string {
+	if path == "" || path == "~" || strings.HasPrefix(path, "~/") || filepath.IsAbs(path) {
+		return path
+	}
+	if absolute, err := filepath.Abs(path); err == nil {
+		return absolute
+	}
+	return path
+}
+
+func setPasswordSource(instance *types.Instance, password, env, file, command string) {
+	instance.Password = password
+	instance.PasswordEnv = env
+	instance.PasswordFile = file
+	instance.PasswordCommand = command
+}
+
+func checkInstance(name string, instance types.Instance) error {
+	if instance.IP == "" {
+		return exitcode.Usagef("--ip is required")
+	}
+	if !instance.IsURL() && instance.Port == 0 {
+		return exitcode.Usagef("--port is required unless --ip is a base URL")
+	}
+	if instance.Password == "" && instance.PasswordSource() == "" {
+		return exitcode.Usagef("a password is required (--password, --password-stdin, --password-env, --password-file or --password-command)")
+	}
+
+	// Load fills Password from an external source; validate the instance as
+	// it will be saved, where that resolved secret is dropped again.
+	saved := instance
+	if saved.PasswordSource() != "" {
+		saved.Password = ""
+	}
+	if err := config.ValidateInstance(name, saved); err != nil {
+		return exitcode.Usagef("%v", err)
+	}
+	return nil
+}
+
+func addSubcommand(args []string) error {
+	f := newInstanceFlags("add")
+	name, err := f.parse(args)
+	if err != nil {
+		return err
+	}
+
+	return modifyConfig(func(cfg *config.Config) error {
+		if _, exists := cfg.GetInstance(name); exists {
+			return exitcode.Usagef("instance '%s' already exists (use 'configure edit')", name)
+		}
+
+		var instance types.Instance
+		if err := f.apply(&instance); err != nil {
+			return err
+		}
+		if err := checkInstance(name, instance); err != nil {
+			return err
+		}
+		if err := f.verify(instance); err != nil {
+			return err
+		}
+
+		cfg.AddInstance(name, instance)
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+
+		return renderInstance(cfg, name, instance, "added")
+	})
+}
+
+func editSubcommand(args []string) error {
+	f := newInstanceFlags("edit")
+	name, err := f.parse(args)
+	if err != nil {
+		return err
+	}
+
+	return modifyConfig(func(cfg *config.Config) error {
+		instance, exists := cfg.GetInstance(name)
+		if !exists {
+			return exitcode.Usagef("instance '%s' not found", name)
+		}
+
+		if err := f.apply(&instance); err != nil {
+			return err
+		}
+		if err := checkInstance(name, instance); err != nil {
+			return err
+		}
+		if err := f.verify(instance); err != nil {
+			return err
+		}
+
+		cfg.AddInstance(name, instance)
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+
+		return renderInstance(cfg, name, instance, "updated")
+	})
+}
+
+func removeSubcommand(args []string) error {
+	flags := flag.NewFlagSet("configure remove", flag.ContinueOnError)
+	yes := flags.Bool("yes", false, "confirm removal")
+
+	name, err := parseNamed(flags, "remove", args)
+	if err != nil {
+		return err
+	}
+
+	return modifyConfig(func(cfg *config.Config) error {
+		if _, exists := cfg.GetInstance(name); !exists {
+			return exitcode.Usagef("instance '%s' not found", name)
+		}
+		if !*yes {
+			return exitcode.Usagef("refusing to remove instance '%s' without --yes", name)
+		}
+
+		cfg.DeleteInstance(name)
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+
+		fmt.Printf("Instance '%s' removed.\n", name)
+		return nil
+	})
+}
+
+func modifyConfig(fn func(cfg *config.Config) error) error {
+	unlock, err := config.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	return fn(cfg)
+}
+
+type instanceView struct {
+	Name        string   `json:"name" yaml:"name"`
+	Address     string   `json:"address" yaml:"address"`
+	Scheme      string   `json:"scheme" yaml:"scheme"`
+	Password    string   `json:"password" yaml:"password"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Group       string   `json:"group,omitempty" yaml:"group,omitempty"`
+	Region      string   `json:"region,omitempty" yaml:"region,omitempty"`
+	Tags        []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+var instanceHeader = []string{"name", "address", "scheme", "password", "description", "group", "region", "tags"}
+
+func newInstanceView(cfg *config.Config, name string, instance types.Instance) instanceView {
+	return instanceView{
+		Name:        name,
+		Address:     instance.Address(),
+		Scheme:      instance.URLScheme(),
+		Password:    passwordStatus(cfg, instance),
+		Description: instance.Description,
+		Group:       instance.Group,
+		Region:      instance.Region,
+		Tags:        instance.Tags,
+	}
+}
+
+func (v instanceView) row() []string {
+	return []string{v.Name, v.Address, v.Scheme, v.Password, v.Description, v.Group, v.Region, strings.Join(v.Tags, ",")}
+}
+
+func renderInstance(cfg *config.Config, name string, instance types.Instance, action string) error {
+	view := newInstanceView(cfg, name, instance)
+
+	return output.Render(os.Stdout, output.CurrentFormat(), output.Result{
+		Data:   view,
+		Header: instanceHeader,
+		Rows:   [][]string{view.row()},
+		Human: func(w io.Writer) {
+			if action != "" {
+				fmt.Fprintf(w, "Instance '%s' %s successfully!\n", name, action)
+			} else {
+				fmt.Fprintf(w, "Instance '%s'\n", name)
+			}
+			fmt.Fprintf(w, "  Address: %s\n", view.Address)
+			if view.Scheme != "http" {
+				fmt.Fprintf(w, "  Scheme: %s\n", view.Scheme)
+			}
+			fmt.Fprintf(w, "  Password: %s\n", displayPassword(cfg, instance))
+			printInstanceLabels(w, instance)
+			if instance.TLS != nil {
+				printTLS(w, instance.TLS)
+			}
+		},
+	})
+}
+
+func printTLS(w io.Writer, tls *types.TLSConfig) {
+	if tls.CAFile != "" {
+		fmt.Fprintf(w, "  CA file: %s\n", tls.CAFile)
+	}
+	if tls.CertFile != "" {
+		fmt.Fprintf(w, "  Client certificate: %s (key: %s)\n", tls.CertFile, tls.KeyFile)
+	}
+	if tls.InsecureSkipVerify {
+		fmt.Fprintln(w, "  Certificate verification: disabled")
+	}
+}
+
+func listSubcommand(args []string) error {
+	flags := flag.NewFlagSet("configure list", flag.ContinueOnError)
+
+	var selector config.Selector
+	selector.BindFlags(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return exitcode.Usagef("%v", err)
+	}
+	selector.Names = flags.Args()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	names, err := cfg.Select(selector)
+	if err != nil {
+		return exitcode.Usagef("%v", err)
+	}
+	sort.Strings(names)
+
+	views := make([]instanceView, 0, len(names))
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		instance, _ := cfg.GetInstance(name)
+		view := newInstanceView(cfg, name, instance)
+		views = append(views, view)
+		rows = append(rows, view.row())
+	}
+
+	return output.Render(os.Stdout, output.CurrentFormat(), output.Result{
+		Data:   views,
+		Header: instanceHeader,
+		Rows:   rows,
+		Human: func(w io.Writer) {
+			if len(names) == 0 {
+				fmt.Fprintln(w, "No instances configured.")
+				return
+			}
+			fmt.Fprintf(w, "Instances (%d):\n", len(names))
+			for _, name := range names {
+				instance, _ := cfg.GetInstance(name)
+				fmt.Fprintf(w, "  - %s (%s)%s\n", name, instance.Address(), instance.Labels())
+			}
+		},
+	})
+}
+
+func showSubcommand(args []string) error {
+	flags := flag.NewFlagSet("configure show", flag.ContinueOnError)
+
+	name, err := parseNamed(flags, "show", args)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	instance, exists := cfg.GetInstance(name)
+	if !exists {
+		return exitcode.Usagef("instance '%s' not found", name)
+	}
+
+	return renderInstance(cfg, name, instance, "")
+}
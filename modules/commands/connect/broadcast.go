@@ -0,0 +1,194 @@
+package connect
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"motor-town-server-tool/modules/config"
+	"motor-town-server-tool/modules/exitcode"
+	"motor-town-server-tool/modules/output"
+)
+
+type Target struct {
+	All      bool
+	Selector config.Selector
+}
+
+func (t Target) resolve(cfg *config.Config) ([]string, error) {
+	selector := t.Selector
+	if t.All {
+		selector = config.Selector{}
+	} else if selector.IsEmpty() {
+		return nil, exitcode.Usagef("no instances selected")
+	}
+
+	names, err := cfg.Select(selector)
+	if err != nil {
+		return nil, exitcode.Usagef("%v", err)
+	}
+	if len(names) == 0 {
+		return nil, exitcode.Usagef("no instances match the selection")
+	}
+	return names, nil
+}
+
+type instanceResult struct {
+	name   string
+	buffer bytes.Buffer
+	result *output.Result
+	err    error
+}
+
+type broadcastRow struct {
+	Instance  string      `json:"instance" yaml:"instance"`
+	Succeeded bool        `json:"succeeded" yaml:"succeeded"`
+	Error     string      `json:"error,omitempty" yaml:"error,omitempty"`
+	Result    interface{} `json:"result,omitempty" yaml:"result,omitempty"`
+}
+
+func broadcast(cfg *config.Config, names []string, command []string, parallel int) error {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	format := output.CurrentFormat()
+	results := make([]*instanceResult, len(names))
+
+	err := runInterruptible(func(ctx context.Context) error {
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, parallel)
+
+		for i, name := range names {
+			res := &instanceResult{name: name}
+			results[i] = res
+
+			instance, err := cfg.ResolveInstance(name)
+			if err != nil {
+				res.err = err
+				continue
+			}
+
+			s, err := newSession(instance, name)
+			if err != nil {
+				res.err = err
+				continue
+			}
+			s.out = &res.buffer
+			if format != output.Table {
+				s.capture = func(result output.Result) {
+					res.result = &result
+				}
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				slots <- struct{}{}
+				defer func() { <-slots }()
+
+				res.err = runCommand(ctx, s, command)
+			}()
+		}
+
+		wg.Wait()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := renderBroadcast(os.Stdout, format, results); err != nil {
+		return err
+	}
+
+	return broadcastError(results)
+}
+
+func renderBroadcast(w io.Writer, format output.Format, results []*instanceResult) error {
+	if format == output.Table {
+		failed := 0
+		for _, res := range results {
+			fmt.Fprintf(w, "=== %s ===\n", res.name)
+			w.Write(res.buffer.Bytes())
+			if res.err != nil {
+				failed++
+				fmt.Fprintf(w, "Error: %v\n", res.err)
+			}
+			fmt.Fprintln(w)
+		}
+
+		fmt.Fprintf(w, "Summary: %d/%d instances succeeded\n", len(results)-failed, len(results))
+		for _, res := range results {
+			if res.err != nil {
+				fmt.Fprintf(w, "  ✗ %s: %v\n", res.name, res.err)
+			} else {
+				fmt.Fprintf(w, "  ✓ %s\n", res.name)
+			}
+		}
+		return nil
+	}
+
+	header := []string{"instance", "succeeded", "error"}
+	for _, res := range results {
+		if res.result != nil {
+			header = append(header, res.result.Header...)
+			break
+		}
+	}
+
+	rows := make([]broadcastRow, 0, len(results))
+	var csvRows [][]string
+
+	for _, res := range results {
+		row := broadcastRow{Instance: res.name, Succeeded: res.err == nil}
+		prefix := []string{res.name, fmt.Sprint(res.err == nil), ""}
+		if res.err != nil {
+			row.Error = res.err.Error()
+			prefix[2] = row.Error
+		}
+
+		var resultRows [][]string
+		if res.result != nil {
+			row.Result = res.result.Data
+			resultRows = res.result.Rows
+		}
+		if len(resultRows) == 0 {
+			resultRows = [][]string{make([]string, len(header)-len(prefix))}
+		}
+		for _, resultRow := range resultRows {
+			csvRows = append(csvRows, append(append([]string{}, prefix...), resultRow...))
+		}
+
+		rows = append(rows, row)
+	}
+
+	return output.Render(w, format, output.Result{
+		Data:   rows,
+		Header: header,
+		Rows:   csvRows,
+	})
+}
+
+func broadcastError(results []*instanceResult) error {
+	errs := make([]error, len(results))
+	failed := 0
+	for i, res := range results {
+		errs[i] = res.err
+		if res.err != nil {
+			failed++
+		}
+	}
+
+	if failed == 0 {
+		return nil
+	}
+
+	return &exitcode.Error{
+		Code: exitcode.Combine(errs),
+		Err:  fmt.Errorf("%d of %d instances failed", failed, len(results)),
+	}
+}
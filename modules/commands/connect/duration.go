@@ -0,0 +1,126 @@
+package connect
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+var durationUnits = map[byte]time.Duration{
+	'w': 7 * 24 * time.Hour,
+	'd': 24 * time.Hour,
+	'h': time.Hour,
+	'm': time.Minute,
+}
+
+// dateLayouts are the absolute dates a ban can be given until, read in the
+// local time zone.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseBanLength converts a ban length into the whole hours the web API
+// takes: a number of hours, a duration such as 30m, 36h, 3d, 1w or 2d12h,
+// or an absolute date to ban until. Partial hours are rounded up, which is
+// reported through rounded.
+func parseBanLength(value string, now time.Time) (hours int, rounded bool, err error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		return n, false, nil
+	}
+
+	length, err := parseDuration(value)
+	if err != nil {
+		until, dateErr := parseDate(value)
+		if dateErr != nil {
+			return 0, false, err
+		}
+		if !until.After(now) {
+			return 0, false, fmt.Errorf("%s is in the past", value)
+		}
+		length = until.Sub(now)
+		if length == math.MaxInt64 {
+			return 0, false, fmt.Errorf("%s is too far in the future", value)
+		}
+	}
+
+	if length <= 0 {
+		return 0, false, fmt.Errorf("%q is not a positive length", value)
+	}
+
+	exact := length.Hours()
+	hours = int(math.Ceil(exact))
+	return hours, float64(hours) != exact, nil
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, fmt.Errorf("empty ban length")
+	}
+	invalid := fmt.Errorf("%q is not a ban length like 24, 30m, 12h, 3d, 1w, 2d12h or 2006-01-02", value)
+
+	var total time.Duration
+	for rest := value; rest != ""; {
+		i := 0
+		for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+			i++
+		}
+		if i == 0 || i == len(rest) {
+			return 0, invalid
+		}
+
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			return 0, invalid
+		}
+		unit, ok := durationUnits[rest[i]]
+		if !ok {
+			return 0, invalid
+		}
+		if time.Duration(n) > (math.MaxInt64-total)/unit {
+			return 0, fmt.Errorf("ban length %q is too long", value)
+		}
+
+		total += time.Duration(n) * unit
+		rest = rest[i+1:]
+	}
+
+	return total, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", value)
+}
+
+// formatRemaining renders a time left as, for example, "2d 3h" or "45m".
+func formatRemaining(d time.Duration) string {
+	if d < time.Minute {
+		return "<1m"
+	}
+
+	d = d.Truncate(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	switch {
+	case days > 0 && hours > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case days > 0:
+		return fmt.Sprintf("%dd", days)
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
@@ -0,0 +1,126 @@
+package connect
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBanLength(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		value   string
+		hours   int
+		rounded bool
+		wantErr bool
+	}{
+		{value: "24", hours: 24},
+		{value: "0", hours: 0},
+		{value: "36h", hours: 36},
+		{value: "3d", hours: 72},
+		{value: "1w", hours: 168},
+		{value: "2d12h", hours: 60},
+		{value: "1w2d3h", hours: 219},
+		{value: "30m", hours: 1, rounded: true},
+		{value: "90m", hours: 2, rounded: true},
+		{value: "1h30m", hours: 2, rounded: true},
+		{value: "120m", hours: 2},
+		{value: "2026-10-17", hours: 12},
+		{value: "2026-10-17 12:30", hours: 25, rounded: true},
+		{value: "2026-10-16T13:00", hours: 1},
+		{value: now.Add(48 * time.Hour).Format(time.RFC3339), hours: 48},
+		{value: "", wantErr: true},
+		{value: "0h", wantErr: true},
+		{value: "h", wantErr: true},
+		{value: "12x", wantErr: true},
+		{value: "12h3", wantErr: true},
+		{value: "1.5h", wantErr: true},
+		{value: "-3h", wantErr: true},
+		{value: "forever", wantErr: true},
+		{value: "2026-10-15", wantErr: true},
+		{value: "2026-10-16 12:00", wantErr: true},
+		{value: "2026-13-01", wantErr: true},
+		{value: "9999-12-31", wantErr: true},
+		{value: "100000000w", wantErr: true},
+		{value: "99999999999999999999h", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		hours, rounded, err := parseBanLength(tt.value, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseBanLength(%q) = %d hours, want an error", tt.value, hours)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseBanLength(%q): %v", tt.value, err)
+			continue
+		}
+		if hours != tt.hours || rounded != tt.rounded {
+			t.Errorf("parseBanLength(%q) = %d, %v; want %d, %v", tt.value, hours, rounded, tt.hours, tt.rounded)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "45m", want: 45 * time.Minute},
+		{value: "12h", want: 12 * time.Hour},
+		{value: "2d12h", want: 60 * time.Hour},
+		{value: "1w1d1h1m", want: 193*time.Hour + time.Minute},
+		{value: "1h1h", want: 2 * time.Hour},
+		{value: "0m", want: 0},
+		{value: "15250w", want: 15250 * 7 * 24 * time.Hour},
+		{value: "15251w", wantErr: true},
+		{value: "15250w1w", wantErr: true},
+		{value: "", wantErr: true},
+		{value: "12", wantErr: true},
+		{value: "d", wantErr: true},
+		{value: "1y", wantErr: true},
+		{value: "1H", wantErr: true},
+		{value: "1d 2h", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDuration(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseDuration(%q) = %v, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseDuration(%q): %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFormatRemaining(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{30 * time.Second, "<1m"},
+		{time.Minute + 59*time.Second, "1m"},
+		{45 * time.Minute, "45m"},
+		{2 * time.Hour, "2h"},
+		{2*time.Hour + 5*time.Minute, "2h 5m"},
+		{24 * time.Hour, "1d"},
+		{51*time.Hour + 30*time.Minute, "2d 3h"},
+	}
+
+	for _, tt := range tests {
+		if got := formatRemaining(tt.d); got != tt.want {
+			t.Errorf("formatRemaining(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
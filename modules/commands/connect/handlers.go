@@ -0,0 +1,483 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"motor-town-server-tool/modules/api"
+	"motor-town-server-tool/modules/exitcode"
+	"motor-town-server-tool/modules/output"
+	"motor-town-server-tool/modules/types"
+)
+
+type session struct {
+	client  *api.Client
+	name    string
+	out     io.Writer
+	format  output.Format
+	capture func(output.Result)
+
+	// input is set when a person is at the keyboard, so ambiguous player
+	// names can be resolved by asking.
+	input lineReader
+}
+
+func newSession(instance types.Instance, instanceName string) (*session, error) {
+	client, err := api.NewClient(instance)
+	if err != nil {
+		return nil, fmt.Errorf("instance '%s': %w", instanceName, err)
+	}
+
+	return &session{
+		client: client,
+		name:   instanceName,
+		out:    os.Stdout,
+		format: output.CurrentFormat(),
+	}, nil
+}
+
+func (s *session) render(result output.Result) error {
+	if s.capture != nil {
+		s.capture(result)
+		return nil
+	}
+	return output.Render(s.out, s.format, result)
+}
+
+func (s *session) progressf(format string, args ...interface{}) {
+	if s.format == output.Table {
+		fmt.Fprintf(s.out, format, args...)
+	}
+}
+
+type playerRow struct {
+	Name     string `json:"name" yaml:"name"`
+	UniqueID string `json:"unique_id" yaml:"unique_id"`
+}
+
+type houseRow struct {
+	House         string `json:"house" yaml:"house"`
+	OwnerUniqueID string `json:"owner_unique_id" yaml:"owner_unique_id"`
+	ExpireTime    string `json:"expire_time" yaml:"expire_time"`
+}
+
+type countResult struct {
+	NumPlayers int `json:"num_players" yaml:"num_players"`
+}
+
+type versionResult struct {
+	Version string `json:"version" yaml:"version"`
+}
+
+type actionResult struct {
+	Action   string `json:"action" yaml:"action"`
+	UniqueID string `json:"unique_id,omitempty" yaml:"unique_id,omitempty"`
+	Hours    int    `json:"hours,omitempty" yaml:"hours,omitempty"`
+	Reason   string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Text     string `json:"text,omitempty" yaml:"text,omitempty"`
+	Response string `json:"response" yaml:"response"`
+	Name     string `json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+func (a actionResult) result(human string) output.Result {
+	return output.Result{
+		Data:   a,
+		Header: []string{"action", "unique_id", "hours", "reason", "text", "response", "name"},
+		Rows:   [][]string{{a.Action, a.UniqueID, strconv.Itoa(a.Hours), a.Reason, a.Text, a.Response, a.Name}},
+		Human: func(w io.Writer) {
+			fmt.Fprintf(w, "✓ %s: %s\n", human, a.Response)
+		},
+	}
+}
+
+func playerResult(players []api.Player, title, empty string) output.Result {
+	rows := make([]playerRow, 0, len(players))
+	csvRows := make([][]string, 0, len(players))
+	for _, player := range players {
+		rows = append(rows, playerRow{Name: player.Name, UniqueID: player.UniqueID})
+		csvRows = append(csvRows, []string{player.Name, player.UniqueID})
+	}
+
+	return output.Result{
+		Data:   rows,
+		Header: []string{"name", "unique_id"},
+		Rows:   csvRows,
+		Human: func(w io.Writer) {
+			if len(rows) == 0 {
+				fmt.Fprintln(w, empty)
+				return
+			}
+			fmt.Fprintf(w, "%s (%d):\n", title, len(rows))
+			for _, player := range rows {
+				fmt.Fprintf(w, "  - %s (ID: %s)\n", player.Name, player.UniqueID)
+			}
+		},
+	}
+}
+
+func handleChatCommand(ctx context.Context, s *session, args commandArgs) error {
+	message := strings.Join(args.words, " ")
+
+	s.progressf("Sending message: %s\n", message)
+
+	response, err := s.client.SendChatMessage(ctx, message)
+	if err != nil {
+		return fmt.Errorf("failed to send chat message: %w", err)
+	}
+
+	action := actionResult{Action: "chat", Text: message, Response: response}
+	return s.render(action.result("Message sent successfully"))
+}
+
+func handlePlayerListCommand(ctx context.Context, s *session, args commandArgs) error {
+	players, err := s.client.GetPlayerList(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get player list: %w", err)
+	}
+
+	return s.render(playerResult(players, "Online players", "No players online"))
+}
+
+func handlePlayerCountCommand(ctx context.Context, s *session, args commandArgs) error {
+	count, err := s.client.GetPlayerCount(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get player count: %w", err)
+	}
+
+	return s.render(output.Result{
+		Data:   countResult{NumPlayers: count},
+		Header: []string{"num_players"},
+		Rows:   [][]string{{strconv.Itoa(count)}},
+		Human: func(w io.Writer) {
+			fmt.Fprintf(w, "Players online: %d\n", count)
+		},
+	})
+}
+
+func handleBanListCommand(ctx context.Context, s *session, args commandArgs) error {
+	players, err := s.client.GetBanList(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get ban list: %w", err)
+	}
+
+	return s.render(banListResult(players, time.Now()))
+}
+
+type banRow struct {
+	Name      string `json:"name" yaml:"name"`
+	UniqueID  string `json:"unique_id" yaml:"unique_id"`
+	Reason    string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	Remaining string `json:"remaining,omitempty" yaml:"remaining,omitempty"`
+}
+
+// banListResult lists bans soonest to expire first, followed by permanent
+// bans and those whose expiry could not be read. Reason and expiry are
+// shown when the server provides them.
+func banListResult(players []api.Player, now time.Time) output.Result {
+	type ban struct {
+		player  api.Player
+		expires time.Time
+		timed   bool
+	}
+
+	bans := make([]ban, len(players))
+	for i, player := range players {
+		expires, timed := player.ExpireTime.Time()
+		bans[i] = ban{player: player, expires: expires, timed: timed}
+	}
+	sort.SliceStable(bans, func(i, j int) bool {
+		if bans[i].timed != bans[j].timed {
+			return bans[i].timed
+		}
+		return bans[i].timed && bans[i].expires.Before(bans[j].expires)
+	})
+
+	rows := make([]banRow, 0, len(bans))
+	csvRows := make([][]string, 0, len(bans))
+	details := make([]string, 0, len(bans))
+	for _, b := range bans {
+		row := banRow{Name: b.player.Name, UniqueID: b.player.UniqueID, Reason: b.player.Reason}
+
+		var detail []string
+		switch {
+		case b.timed && b.expires.After(now):
+			row.ExpiresAt = b.expires.Local().Format(time.RFC3339)
+			row.Remaining = formatRemaining(b.expires.Sub(now))
+			detail = append(detail, fmt.Sprintf("expires %s (in %s)", b.expires.Local().Format("2006-01-02 15:04"), row.Remaining))
+		case b.timed:
+			row.ExpiresAt = b.expires.Local().Format(time.RFC3339)
+			row.Remaining = "expired"
+			detail = append(detail, "expired")
+		case b.player.ExpireTime != "":
+			row.ExpiresAt = string(b.player.ExpireTime)
+			detail = append(detail, "expires "+row.ExpiresAt)
+		}
+		if row.Reason != "" {
+			detail = append(detail, "reason: "+row.Reason)
+		}
+
+		rows = append(rows, row)
+		csvRows = append(csvRows, []string{row.Name, row.UniqueID, row.Reason, row.ExpiresAt, row.Remaining})
+		details = append(details, strings.Join(detail, ", "))
+	}
+
+	return output.Result{
+		Data:   rows,
+		Header: []string{"name", "unique_id", "reason", "expires_at", "remaining"},
+		Rows:   csvRows,
+		Human: func(w io.Writer) {
+			if len(rows) == 0 {
+				fmt.Fprintln(w, "No banned players")
+				return
+			}
+			fmt.Fprintf(w, "Banned players (%d):\n", len(rows))
+			for i, row := range rows {
+				if details[i] == "" {
+					fmt.Fprintf(w, "  - %s (ID: %s)\n", row.Name, row.UniqueID)
+				} else {
+					fmt.Fprintf(w, "  - %s (ID: %s) %s\n", row.Name, row.UniqueID, details[i])
+				}
+			}
+		},
+	}
+}
+
+func handleKickCommand(ctx context.Context, s *session, args commandArgs) error {
+	player, err := s.lookupPlayer(ctx, strings.Join(args.words, " "), false)
+	if err != nil {
+		return err
+	}
+
+	s.progressf("Kicking player %s\n", describePlayer(player))
+
+	response, err := s.client.KickPlayer(ctx, player.UniqueID)
+	if err != nil {
+		return fmt.Errorf("failed to kick player: %w", err)
+	}
+
+	action := actionResult{Action: "kick", UniqueID: player.UniqueID, Name: player.Name, Response: response}
+	return s.render(action.result("Player kicked successfully"))
+}
+
+func handleBanCommand(ctx context.Context, s *session, args commandArgs) error {
+	hours, rounded, err := banHours(args)
+	if err != nil {
+		return err
+	}
+
+	reason := args.value("reason").(string)
+	if len(args.words) > 2 {
+		if args.isSet("reason") {
+			return exitcode.Usagef("give the reason either with --reason or after the ban length, not both")
+		}
+		reason = strings.Join(args.words[2:], " ")
+	}
+
+	player, err := s.lookupPlayer(ctx, args.words[0], false)
+	if err != nil {
+		return err
+	}
+
+	s.progressf("Banning player %s", describePlayer(player))
+	if hours == 1 {
+		s.progressf(" for 1 hour")
+	} else if hours > 0 {
+		s.progressf(" for %d hours", hours)
+	}
+	if rounded {
+		s.progressf(" (rounded up to whole hours)")
+	}
+	if reason != "" {
+		s.progressf(" (reason: %s)", reason)
+	}
+	s.progressf("\n")
+
+	response, err := s.client.BanPlayer(ctx, player.UniqueID, hours, reason)
+	if err != nil {
+		return fmt.Errorf("failed to ban player: %w", err)
+	}
+
+	action := actionResult{Action: "ban", UniqueID: player.UniqueID, Name: player.Name, Hours: hours, Reason: reason, Response: response}
+	return s.render(action.result("Player banned successfully"))
+}
+
+// banHours returns the ban length given as the positional length argument,
+// --hours or --duration, of which at most one may be used, and whether it
+// was rounded up to a whole hour.
+func banHours(args commandArgs) (int, bool, error) {
+	hours, rounded, given := 0, false, 0
+
+	if len(args.words) > 1 {
+		h, r, err := parseBanLength(args.words[1], time.Now())
+		if err != nil {
+			return 0, false, exitcode.Usagef("invalid ban length: %v (use --reason to give a reason)", err)
+		}
+		hours, rounded = h, r
+		given++
+	}
+	if args.isSet("hours") {
+		hours = args.value("hours").(int)
+		given++
+	}
+	if args.isSet("duration") {
+		h, r, err := parseBanLength(args.value("duration").(string), time.Now())
+		if err != nil {
+			return 0, false, exitcode.Usagef("invalid --duration: %v", err)
+		}
+		hours, rounded = h, r
+		given++
+	}
+
+	if given > 1 {
+		return 0, false, exitcode.Usagef("give the ban length only once: positionally, with --hours or with --duration")
+	}
+	if hours < 0 {
+		return 0, false, exitcode.Usagef("ban length cannot be negative")
+	}
+	return hours, rounded, nil
+}
+
+func handleUnbanCommand(ctx context.Context, s *session, args commandArgs) error {
+	player, err := s.lookupPlayer(ctx, strings.Join(args.words, " "), true)
+	if err != nil {
+		return err
+	}
+
+	s.progressf("Unbanning player %s\n", describePlayer(player))
+
+	response, err := s.client.UnbanPlayer(ctx, player.UniqueID)
+	if err != nil {
+		return fmt.Errorf("failed to unban player: %w", err)
+	}
+
+	action := actionResult{Action: "unban", UniqueID: player.UniqueID, Name: player.Name, Response: response}
+	return s.render(action.result("Player unbanned successfully"))
+}
+
+type whoisRow struct {
+	Name     string `json:"name" yaml:"name"`
+	UniqueID string `json:"unique_id" yaml:"unique_id"`
+	Online   bool   `json:"online" yaml:"online"`
+	Banned   bool   `json:"banned" yaml:"banned"`
+}
+
+func handleWhoisCommand(ctx context.Context, s *session, args commandArgs) error {
+	query := strings.Join(args.words, " ")
+
+	online, err := s.client.GetPlayerList(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get player list: %w", err)
+	}
+	banned, err := s.client.GetBanList(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get ban list: %w", err)
+	}
+
+	known := make(map[string]*whoisRow)
+	var players []api.Player
+	for _, list := range [][]api.Player{online, banned} {
+		for _, player := range list {
+			if known[player.UniqueID] == nil {
+				known[player.UniqueID] = &whoisRow{Name: player.Name, UniqueID: player.UniqueID}
+				players = append(players, player)
+			}
+		}
+	}
+	for _, player := range online {
+		known[player.UniqueID].Online = true
+	}
+	for _, player := range banned {
+		known[player.UniqueID].Banned = true
+	}
+
+	matches, _ := findPlayers(players, query)
+	if len(matches) == 0 {
+		return exitcode.Usagef("no player matching '%s' is online or banned", query)
+	}
+
+	rows := make([]whoisRow, 0, len(matches))
+	csvRows := make([][]string, 0, len(matches))
+	for _, player := range matches {
+		row := *known[player.UniqueID]
+		rows = append(rows, row)
+		csvRows = append(csvRows, []string{row.Name, row.UniqueID, strconv.FormatBool(row.Online), strconv.FormatBool(row.Banned)})
+	}
+
+	return s.render(output.Result{
+		Data:   rows,
+		Header: []string{"name", "unique_id", "online", "banned"},
+		Rows:   csvRows,
+		Human: func(w io.Writer) {
+			for _, row := range rows {
+				var status []string
+				if row.Online {
+					status = append(status, "online")
+				}
+				if row.Banned {
+					status = append(status, "banned")
+				}
+				fmt.Fprintf(w, "  - %s (ID: %s) %s\n", row.Name, row.UniqueID, strings.Join(status, ", "))
+			}
+		},
+	})
+}
+
+func handleVersionCommand(ctx context.Context, s *session, args commandArgs) error {
+	version, err := s.client.GetVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get version: %w", err)
+	}
+
+	return s.render(output.Result{
+		Data:   versionResult{Version: version},
+		Header: []string{"version"},
+		Rows:   [][]string{{version}},
+		Human: func(w io.Writer) {
+			fmt.Fprintf(w, "Server version: %s\n", version)
+		},
+	})
+}
+
+func handleHousingCommand(ctx context.Context, s *session, args commandArgs) error {
+	houses, err := s.client.GetHousingList(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get housing list: %w", err)
+	}
+
+	houseNames := make([]string, 0, len(houses))
+	for houseName := range houses {
+		houseNames = append(houseNames, houseName)
+	}
+	sort.Strings(houseNames)
+
+	rows := make([]houseRow, 0, len(houseNames))
+	csvRows := make([][]string, 0, len(houseNames))
+	for _, houseName := range houseNames {
+		house := houses[houseName]
+		rows = append(rows, houseRow{House: houseName, OwnerUniqueID: house.OwnerUniqueID, ExpireTime: house.ExpireTime})
+		csvRows = append(csvRows, []string{houseName, house.OwnerUniqueID, house.ExpireTime})
+	}
+
+	return s.render(output.Result{
+		Data:   rows,
+		Header: []string{"house", "owner_unique_id", "expire_time"},
+		Rows:   csvRows,
+		Human: func(w io.Writer) {
+			if len(rows) == 0 {
+				fmt.Fprintln(w, "No housing data available")
+				return
+			}
+			fmt.Fprintf(w, "Housing list (%d entries):\n", len(rows))
+			for _, house := range rows {
+				fmt.Fprintf(w, "  - %s (Owner: %s, Expires: %s)\n", house.House, house.OwnerUniqueID, house.ExpireTime)
+			}
+		},
+	})
+}
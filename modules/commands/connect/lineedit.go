@@ -0,0 +1,299 @@
+package connect
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"motor-town-server-tool/modules/api"
+	"motor-town-server-tool/modules/config"
+
+	"github.com/peterh/liner"
+	"golang.org/x/term"
+)
+
+const (
+	completionCacheTTL = 10 * time.Second
+	completionTimeout  = 2 * time.Second
+)
+
+// errAborted is returned by ReadLine when Ctrl+C discards the line.
+var errAborted = errors.New("aborted")
+
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+	Remember(line string)
+	Close() error
+}
+
+// newLineReader returns a line editor with history and completion when
+// stdin is a terminal, and a plain reader over scanner otherwise so piped
+// input keeps working.
+func newLineReader(scanner *bufio.Scanner, s *session) lineReader {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !liner.TerminalSupported() {
+		return &scannerReader{scanner: scanner}
+	}
+
+	state := liner.NewLiner()
+	state.SetCtrlCAborts(true)
+	state.SetTabCompletionStyle(liner.TabPrints)
+	state.SetWordCompleter(newCompleter(s).complete)
+
+	editor := &editorReader{state: state}
+	if path, err := config.HistoryPath(s.name); err == nil {
+		editor.historyPath = path
+		editor.loadHistory()
+	}
+	return editor
+}
+
+type scannerReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r *scannerReader) ReadLine(prompt string) (string, error) {
+	fmt.Print(prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+func (r *scannerReader) Remember(line string) {}
+
+func (r *scannerReader) Close() error {
+	return nil
+}
+
+type editorReader struct {
+	state       *liner.State
+	historyPath string
+}
+
+func (r *editorReader) ReadLine(prompt string) (string, error) {
+	line, err := r.state.Prompt(prompt)
+	if errors.Is(err, liner.ErrPromptAborted) {
+		return "", errAborted
+	}
+	return line, err
+}
+
+func (r *editorReader) Remember(line string) {
+	r.state.AppendHistory(line)
+}
+
+func (r *editorReader) Close() error {
+	if err := r.saveHistory(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save shell history: %v\n", err)
+	}
+	return r.state.Close()
+}
+
+func (r *editorReader) loadHistory() {
+	file, err := os.Open(r.historyPath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	r.state.ReadHistory(file)
+}
+
+func (r *editorReader) saveHistory() error {
+	if r.historyPath == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.historyPath), 0700); err != nil {
+		return err
+	}
+
+	// History can hold chat messages and ban reasons, so keep it private.
+	file, err := os.OpenFile(r.historyPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.state.WriteHistory(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// completer completes command names and, for commands that take a player,
+// the names and unique IDs of players currently on the server (or, for
+// unban, on the ban list). Lists are fetched lazily and cached briefly.
+type completer struct {
+	session *session
+
+	mu      sync.Mutex
+	cache   map[string][]api.Player
+	fetched map[string]time.Time
+}
+
+func newCompleter(s *session) *completer {
+	return &completer{
+		session: s,
+		cache:   make(map[string][]api.Player),
+		fetched: make(map[string]time.Time),
+	}
+}
+
+func (c *completer) complete(line string, pos int) (string, []string, string) {
+	head, matches, tail := c.candidates(line, pos)
+	if len(matches) == 1 && !strings.HasPrefix(tail, " ") {
+		// A single match is complete, so move on to the next argument.
+		matches[0] += " "
+	}
+	return head, matches, tail
+}
+
+// candidates returns the completions for the word under the cursor. pos is
+// a rune index, as liner counts the cursor position in runes.
+func (c *completer) candidates(line string, pos int) (string, []string, string) {
+	runes := []rune(line)
+	head, tail := string(runes[:pos]), string(runes[pos:])
+
+	scan := scanLine(head)
+	word := scan.last
+	head, typed := head[:word.start], head[word.start:]
+
+	if len(scan.words) == 0 {
+		return head, extendWord(registry.names(), typed, word), tail
+	}
+
+	cmd, ok := registry.lookup(scan.words[0])
+	if !ok {
+		return head, nil, tail
+	}
+
+	if word.quote == 0 && strings.HasPrefix(word.value, "-") {
+		var flags []string
+		cmd.flagSet().VisitAll(func(f *flag.Flag) {
+			flags = append(flags, "--"+f.Name)
+		})
+		return head, extendWord(flags, typed, word), tail
+	}
+	if len(scan.words) > 1 {
+		return head, nil, tail
+	}
+
+	var candidates []string
+	switch cmd.complete {
+	case completeOnline:
+		candidates = playerCandidates(c.players("players"))
+	case completeBanned:
+		candidates = playerCandidates(c.players("banlist"))
+	case completeAnyPlayer:
+		candidates = append(playerCandidates(c.players("players")), playerCandidates(c.players("banlist"))...)
+	case completeCommand:
+		candidates = registry.names()
+	}
+
+	return head, extendWord(candidates, typed, word), tail
+}
+
+func (c *completer) players(list string) []api.Player {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.fetched[list]) < completionCacheTTL {
+		return c.cache[list]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	var players []api.Player
+	var err error
+	if list == "banlist" {
+		players, err = c.session.client.GetBanList(ctx)
+	} else {
+		players, err = c.session.client.GetPlayerList(ctx)
+	}
+	if err != nil {
+		// Completion is best effort; keep whatever was fetched last.
+		return c.cache[list]
+	}
+
+	c.cache[list] = players
+	c.fetched[list] = time.Now()
+	return players
+}
+
+func playerCandidates(players []api.Player) []string {
+	candidates := make([]string, 0, len(players)*2)
+	for _, player := range players {
+		if player.UniqueID != "" {
+			candidates = append(candidates, player.UniqueID)
+		}
+		if player.Name != "" {
+			candidates = append(candidates, player.Name)
+		}
+	}
+	return candidates
+}
+
+// extendWord returns the completions of word among candidates. Each one is
+// typed, the word exactly as entered with the user's letter case and
+// quoting, followed by the rest of a candidate it prefixes (ignoring case),
+// quoted to match. liner replaces the typed word with these, so anything
+// else would lose what was typed.
+func extendWord(candidates []string, typed string, word partialWord) []string {
+	prefix := []rune(word.value)
+
+	var matches []string
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		runes := []rune(candidate)
+		if len(runes) < len(prefix) || !strings.EqualFold(string(runes[:len(prefix)]), word.value) {
+			continue
+		}
+
+		var completion string
+		if typed == "" {
+			completion = quoteWord(candidate)
+		} else {
+			completion = typed + quoteRest(string(runes[len(prefix):]), word.quote)
+		}
+		if !seen[completion] {
+			seen[completion] = true
+			matches = append(matches, completion)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// quoteRest quotes the rest of a completed word so it continues a word
+// that has quote left open, closing the quote at the end.
+func quoteRest(rest string, quote rune) string {
+	switch quote {
+	case '\'':
+		return strings.ReplaceAll(rest, "'", `'\''`) + "'"
+	case '"':
+		return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(rest) + `"`
+	default:
+		var b strings.Builder
+		for _, r := range rest {
+			if strings.ContainsRune(" \t'\"\\", r) {
+				b.WriteRune('\\')
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	}
+}
@@ -0,0 +1,96 @@
+package connect
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"motor-town-server-tool/modules/api"
+)
+
+func newTestCompleter(online, banned []api.Player) *completer {
+	c := newCompleter(nil)
+	c.cache["players"], c.fetched["players"] = online, time.Now()
+	c.cache["banlist"], c.fetched["banlist"] = banned, time.Now()
+	return c
+}
+
+func TestCompleterCandidates(t *testing.T) {
+	c := newTestCompleter(
+		[]api.Player{{Name: "Zoë", UniqueID: "101"}, {Name: "Zoë Racer", UniqueID: "102"}, {Name: "O'Brien", UniqueID: "103"}},
+		[]api.Player{{Name: "Bob", UniqueID: "201"}},
+	)
+
+	tests := []struct {
+		line    string
+		pos     int // rune index of the cursor; -1 for the end of line
+		head    string
+		matches []string
+		tail    string
+	}{
+		{line: "ki", pos: -1, head: "", matches: []string{"kick"}},
+		{line: "help ba", pos: -1, head: "help ", matches: []string{"ban", "banlist"}},
+		{line: "ban 101 --re", pos: -1, head: "ban 101 ", matches: []string{"--reason"}},
+		{line: "kick ", pos: -1, head: "kick ", matches: []string{"'O'\\''Brien'", "'Zoë Racer'", "101", "102", "103", "Zoë"}},
+		{line: "kick Zoë", pos: -1, head: "kick ", matches: []string{"Zoë", `Zoë\ Racer`}},
+		{line: "kick zo", pos: -1, head: "kick ", matches: []string{"zoë", `zoë\ Racer`}},
+		{line: "whois 'Zo", pos: -1, head: "whois ", matches: []string{"'Zoë Racer'", "'Zoë'"}},
+		{line: "kick 'Zoë R", pos: -1, head: "kick ", matches: []string{"'Zoë Racer'"}},
+		{line: `kick "zoë r`, pos: -1, head: "kick ", matches: []string{`"zoë racer"`}},
+		{line: `kick Zoë\ R`, pos: -1, head: "kick ", matches: []string{`Zoë\ Racer`}},
+		{line: `kick "O'`, pos: -1, head: "kick ", matches: []string{`"O'Brien"`}},
+		{line: "kick O", pos: -1, head: "kick ", matches: []string{`O\'Brien`}},
+		{line: "unban b", pos: -1, head: "unban ", matches: []string{"bob"}},
+		{line: "kick Zoë 5h", pos: 8, head: "kick ", matches: []string{"Zoë", `Zoë\ Racer`}, tail: " 5h"},
+		{line: "whois Zoë R", pos: 10, head: "whois Zoë ", tail: "R"},
+		{line: "kick Zoë R", pos: -1, head: "kick Zoë "},
+		{line: "kick x", pos: -1, head: "kick "},
+		{line: "nope a", pos: -1, head: "nope "},
+	}
+
+	for _, tt := range tests {
+		pos := tt.pos
+		if pos < 0 {
+			pos = utf8.RuneCountInString(tt.line)
+		}
+
+		head, matches, tail := c.candidates(tt.line, pos)
+		if head != tt.head || tail != tt.tail || !reflect.DeepEqual(matches, tt.matches) {
+			t.Errorf("candidates(%q, %d) = %q, %q, %q; want %q, %q, %q", tt.line, pos, head, matches, tail, tt.head, tt.matches, tt.tail)
+		}
+	}
+}
+
+// TestCompletionsReadBack checks that every completion of a player name is
+// read back by the shell as that name.
+func TestCompletionsReadBack(t *testing.T) {
+	names := []string{"Zoë", "Zoë Racer", "O'Brien", `back\slash`, `say "hi"`}
+	players := make([]api.Player, len(names))
+	for i, name := range names {
+		players[i] = api.Player{Name: name}
+	}
+	c := newTestCompleter(players, nil)
+
+	for _, name := range names {
+		for _, typed := range []string{"", string([]rune(name)[:1]), "'" + string([]rune(name)[:1]), `"` + string([]rune(name)[:1])} {
+			line := "kick " + typed
+			_, matches, _ := c.candidates(line, utf8.RuneCountInString(line))
+
+			found := false
+			for _, match := range matches {
+				words, err := splitWords("kick " + match)
+				if err != nil {
+					t.Errorf("completion %q of %q does not parse: %v", match, line, err)
+					continue
+				}
+				if len(words) == 2 && words[1] == name {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("no completion of %q reads back as %q: %q", line, name, matches)
+			}
+		}
+	}
+}
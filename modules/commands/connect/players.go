@@ -0,0 +1,226 @@
+package connect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"motor-town-server-tool/modules/api"
+	"motor-town-server-tool/modules/exitcode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var errCancelled = errors.New("cancelled")
+
+// Match tiers, from closest to loosest. findPlayers only returns players
+// from the closest tier that matched anyone.
+const (
+	matchUniqueID = iota
+	matchExactName
+	matchNamePrefix
+	matchNameContains
+	matchNameFuzzy
+	noMatch
+)
+
+func matchTier(player api.Player, query string) int {
+	if player.UniqueID != "" && player.UniqueID == query {
+		return matchUniqueID
+	}
+
+	name, q := normalizeName(player.Name), normalizeName(query)
+	switch {
+	case q == "":
+		return noMatch
+	case name == q:
+		return matchExactName
+	case strings.HasPrefix(name, q):
+		return matchNamePrefix
+	case strings.Contains(name, q):
+		return matchNameContains
+	case isSubsequence(q, name):
+		return matchNameFuzzy
+	default:
+		return noMatch
+	}
+}
+
+// normalizeName lowercases s, strips accents and drops everything but
+// letters and digits, so "[CLAN] Bob_99" can be found with "clanbob" and
+// "Zoë" with "zoe".
+func normalizeName(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isSubsequence(needle, haystack string) bool {
+	rest := []rune(needle)
+	for _, r := range haystack {
+		if len(rest) == 0 {
+			break
+		}
+		if r == rest[0] {
+			rest = rest[1:]
+		}
+	}
+	return len(rest) == 0
+}
+
+// findPlayers returns the players matching query most closely, by name,
+// along with the tier they matched at.
+func findPlayers(players []api.Player, query string) ([]api.Player, int) {
+	best := noMatch
+	var matches []api.Player
+	for _, player := range players {
+		tier := matchTier(player, query)
+		if tier == noMatch || tier > best {
+			continue
+		}
+		if tier < best {
+			best = tier
+			matches = nil
+		}
+		matches = append(matches, player)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return strings.ToLower(matches[i].Name) < strings.ToLower(matches[j].Name)
+	})
+	return matches, best
+}
+
+func looksLikeUniqueID(query string) bool {
+	if query == "" {
+		return false
+	}
+	for _, r := range query {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupPlayer resolves a name, partial name or unique ID against the
+// online players or, with banned set, the ban list.
+func (s *session) lookupPlayer(ctx context.Context, query string, banned bool) (api.Player, error) {
+	var players []api.Player
+	var err error
+	if banned {
+		players, err = s.client.GetBanList(ctx)
+	} else {
+		players, err = s.client.GetPlayerList(ctx)
+	}
+	if err != nil {
+		return api.Player{}, fmt.Errorf("failed to look up player: %w", err)
+	}
+
+	return s.resolvePlayer(query, players)
+}
+
+// resolvePlayer picks the single player query refers to. A query that
+// matches nobody but is all digits is taken as a unique ID, so players who
+// are offline can still be targeted.
+//
+// Without an interactive shell to confirm in, only a unique ID, an exact
+// name or a name prefix matching one player is acted on. The shell also
+// accepts substring and letter-by-letter matches, but asks before acting on
+// them, and offers several matches as a choice.
+func (s *session) resolvePlayer(query string, players []api.Player) (api.Player, error) {
+	matches, tier := findPlayers(players, query)
+
+	if len(matches) == 0 {
+		if looksLikeUniqueID(query) {
+			return api.Player{UniqueID: query}, nil
+		}
+		return api.Player{}, exitcode.Usagef("no player matching '%s'", query)
+	}
+
+	if s.input == nil {
+		if tier > matchNamePrefix {
+			return api.Player{}, exitcode.Usagef("no player named '%s'; did you mean %s? use the exact name or the unique ID", query, listPlayers(matches))
+		}
+		if len(matches) > 1 {
+			return api.Player{}, exitcode.Usagef("'%s' matches %d players: %s; use a longer name or the unique ID", query, len(matches), listPlayers(matches))
+		}
+		return matches[0], nil
+	}
+
+	if len(matches) > 1 {
+		return s.choosePlayer(query, matches)
+	}
+	if tier > matchNamePrefix {
+		return s.confirmPlayer(query, matches[0])
+	}
+	return matches[0], nil
+}
+
+func listPlayers(players []api.Player) string {
+	names := make([]string, len(players))
+	for i, player := range players {
+		names[i] = fmt.Sprintf("%s (%s)", player.Name, player.UniqueID)
+	}
+	return strings.Join(names, ", ")
+}
+
+func (s *session) confirmPlayer(query string, player api.Player) (api.Player, error) {
+	fmt.Fprintf(s.out, "'%s' loosely matches %s\n", query, describePlayer(player))
+
+	line, err := s.input.ReadLine("Is this the player you meant? [y/N]: ")
+	if err != nil {
+		fmt.Fprintln(s.out)
+		return api.Player{}, errCancelled
+	}
+
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return player, nil
+	default:
+		return api.Player{}, errCancelled
+	}
+}
+
+func (s *session) choosePlayer(query string, matches []api.Player) (api.Player, error) {
+	fmt.Fprintf(s.out, "'%s' matches %d players:\n", query, len(matches))
+	for i, player := range matches {
+		fmt.Fprintf(s.out, "  [%d] %s (ID: %s)\n", i+1, player.Name, player.UniqueID)
+	}
+
+	line, err := s.input.ReadLine("Select player (0 to cancel): ")
+	if err != nil {
+		fmt.Fprintln(s.out)
+		return api.Player{}, errCancelled
+	}
+
+	choiceStr := strings.TrimSpace(line)
+	choice, err := strconv.Atoi(choiceStr)
+	if err != nil || choice < 0 || choice > len(matches) {
+		return api.Player{}, exitcode.Usagef("invalid choice: %s", choiceStr)
+	}
+	if choice == 0 {
+		return api.Player{}, errCancelled
+	}
+
+	return matches[choice-1], nil
+}
+
+// describePlayer formats a resolved player for progress messages.
+func describePlayer(player api.Player) string {
+	if player.Name == "" {
+		return fmt.Sprintf("with ID: %s", player.UniqueID)
+	}
+	return fmt.Sprintf("%s (ID: %s)", player.Name, player.UniqueID)
+}
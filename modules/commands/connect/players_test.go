@@ -0,0 +1,54 @@
+package connect
+
+import (
+	"testing"
+
+	"motor-town-server-tool/modules/api"
+)
+
+func TestFindPlayers(t *testing.T) {
+	players := []api.Player{
+		{Name: "Zoë", UniqueID: "101"},
+		{Name: "Zoë Racer", UniqueID: "102"},
+		{Name: "[CLAN] Bob_99", UniqueID: "103"},
+		{Name: "José", UniqueID: "104"},
+		{Name: "Ångström", UniqueID: "105"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+		tier  int
+	}{
+		{query: "101", want: []string{"Zoë"}, tier: matchUniqueID},
+		{query: "zoe", want: []string{"Zoë"}, tier: matchExactName},
+		{query: "ZOË", want: []string{"Zoë"}, tier: matchExactName},
+		{query: "zoe r", want: []string{"Zoë Racer"}, tier: matchNamePrefix},
+		{query: "jose", want: []string{"José"}, tier: matchExactName},
+		{query: "angstrom", want: []string{"Ångström"}, tier: matchExactName},
+		{query: "clanbob", want: []string{"[CLAN] Bob_99"}, tier: matchNamePrefix},
+		{query: "bob", want: []string{"[CLAN] Bob_99"}, tier: matchNameContains},
+		{query: "clnbob", want: []string{"[CLAN] Bob_99"}, tier: matchNameFuzzy},
+		{query: "zo", want: []string{"Zoë", "Zoë Racer"}, tier: matchNamePrefix},
+		{query: "nobody", tier: noMatch},
+		{query: "!!", tier: noMatch},
+	}
+
+	for _, tt := range tests {
+		matches, tier := findPlayers(players, tt.query)
+		var names []string
+		for _, player := range matches {
+			names = append(names, player.Name)
+		}
+		if tier != tt.tier || len(names) != len(tt.want) {
+			t.Errorf("findPlayers(%q) = %q at tier %d, want %q at tier %d", tt.query, names, tier, tt.want, tt.tier)
+			continue
+		}
+		for i := range names {
+			if names[i] != tt.want[i] {
+				t.Errorf("findPlayers(%q) = %q, want %q", tt.query, names, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package connect
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"motor-town-server-tool/modules/exitcode"
+)
+
+// errDisconnect is returned by the exit command to end the shell.
+var errDisconnect = errors.New("disconnect")
+
+// completion says what Tab offers for a command's first argument.
+type completion int
+
+const (
+	completeNothing completion = iota
+	completeOnline
+	completeBanned
+	completeAnyPlayer
+	completeCommand
+)
+
+// shellCommand is one command of the connect shell. Help, completion,
+// argument checks and dispatch for both the shell and exec are driven by
+// these registrations.
+type shellCommand struct {
+	name        string
+	aliases     []string
+	usage       string
+	description string
+	minArgs     int
+	maxArgs     int // -1 for no limit
+	complete    completion
+	shellOnly   bool
+
+	// rawArgs makes the shell pass the rest of the line through as a single
+	// argument, keeping quotes and whitespace as typed.
+	rawArgs bool
+
+	// flags defines the command's flags, which may appear anywhere after
+	// the command name.
+	flags func(fs *flag.FlagSet)
+
+	run func(ctx context.Context, s *session, args commandArgs) error
+}
+
+// commandArgs holds a command's positional arguments and parsed flags.
+type commandArgs struct {
+	words []string
+	flags *flag.FlagSet
+	set   map[string]bool
+}
+
+func (a commandArgs) isSet(name string) bool {
+	return a.set[name]
+}
+
+func (a commandArgs) value(name string) interface{} {
+	return a.flags.Lookup(name).Value.(flag.Getter).Get()
+}
+
+func (c *shellCommand) synopsis() string {
+	return strings.TrimSpace(c.name + " " + c.usage)
+}
+
+func (c *shellCommand) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if c.flags != nil {
+		c.flags(fs)
+	}
+	return fs
+}
+
+// parseArgs separates flags from positional arguments, allowing them to be
+// mixed, and checks the number of positional arguments. "--" ends flags.
+func (c *shellCommand) parseArgs(words []string) (commandArgs, error) {
+	args := commandArgs{flags: c.flagSet(), set: make(map[string]bool)}
+
+	if c.flags == nil {
+		args.words = words
+	}
+	for c.flags != nil && len(words) > 0 {
+		if err := args.flags.Parse(words); err != nil {
+			if err == flag.ErrHelp {
+				return args, err
+			}
+			return args, exitcode.Usagef("%s: %v", c.name, err)
+		}
+
+		rest := args.flags.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if consumed := len(words) - len(rest); consumed > 0 && words[consumed-1] == "--" {
+			args.words = append(args.words, rest...)
+			break
+		}
+		args.words = append(args.words, rest[0])
+		words = rest[1:]
+	}
+	args.flags.Visit(func(f *flag.Flag) {
+		args.set[f.Name] = true
+	})
+
+	if len(args.words) < c.minArgs || (c.maxArgs >= 0 && len(args.words) > c.maxArgs) {
+		return args, exitcode.Usagef("usage: %s", c.synopsis())
+	}
+	return args, nil
+}
+
+type commandRegistry struct {
+	commands []*shellCommand
+	index    map[string]*shellCommand
+}
+
+func newCommandRegistry() *commandRegistry {
+	return &commandRegistry{index: make(map[string]*shellCommand)}
+}
+
+func (r *commandRegistry) register(cmd *shellCommand) {
+	for _, name := range append([]string{cmd.name}, cmd.aliases...) {
+		if _, exists := r.index[name]; exists {
+			panic(fmt.Sprintf("shell command %q registered twice", name))
+		}
+		r.index[name] = cmd
+	}
+	r.commands = append(r.commands, cmd)
+}
+
+func (r *commandRegistry) lookup(name string) (*shellCommand, bool) {
+	cmd, ok := r.index[strings.ToLower(name)]
+	return cmd, ok
+}
+
+// names returns every command name and alias, sorted.
+func (r *commandRegistry) names() []string {
+	names := make([]string, 0, len(r.index))
+	for name := range r.index {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// splitLine splits a line typed into the shell into words, leaving the
+// arguments of rawArgs commands untouched.
+func (r *commandRegistry) splitLine(line string) ([]string, error) {
+	line = strings.TrimSpace(line)
+
+	name, rest := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		name, rest = line[:i], strings.TrimLeft(line[i:], " \t")
+	}
+	if cmd, ok := r.lookup(name); ok && cmd.rawArgs {
+		if rest == "" {
+			return []string{name}, nil
+		}
+		return []string{name, rest}, nil
+	}
+
+	return splitWords(line)
+}
+
+// dispatch runs a command line already split into words. Commands marked
+// shellOnly are refused unless fromShell is set.
+func (r *commandRegistry) dispatch(ctx context.Context, s *session, parts []string, fromShell bool) error {
+	cmd, ok := r.lookup(parts[0])
+	if !ok {
+		return exitcode.Usagef("unknown command: %s (type 'help' for available commands)", parts[0])
+	}
+	if cmd.shellOnly && !fromShell {
+		return exitcode.Usagef("'%s' is only available in the interactive shell", cmd.name)
+	}
+
+	args, err := cmd.parseArgs(parts[1:])
+	if err == flag.ErrHelp {
+		r.writeCommandHelp(s.out, cmd)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return cmd.run(ctx, s, args)
+}
+
+func (r *commandRegistry) writeHelp(w io.Writer) {
+	labels := make([]string, len(r.commands))
+	width := 0
+	for i, cmd := range r.commands {
+		labels[i] = strings.TrimSpace(strings.Join(append([]string{cmd.name}, cmd.aliases...), ", ") + " " + cmd.usage)
+		if len(labels[i]) > width {
+			width = len(labels[i])
+		}
+	}
+
+	fmt.Fprintln(w, "Available commands:")
+	for i, cmd := range r.commands {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, labels[i], cmd.description)
+	}
+	fmt.Fprintln(w)
+}
+
+func (r *commandRegistry) writeCommandHelp(w io.Writer, cmd *shellCommand) {
+	fmt.Fprintf(w, "usage: %s\n", cmd.synopsis())
+	fmt.Fprintf(w, "  %s\n", cmd.description)
+	if len(cmd.aliases) > 0 {
+		fmt.Fprintf(w, "  aliases: %s\n", strings.Join(cmd.aliases, ", "))
+	}
+	if cmd.shellOnly {
+		fmt.Fprintln(w, "  only available in the interactive shell")
+	}
+
+	cmd.flagSet().VisitAll(func(f *flag.Flag) {
+		kind, usage := flag.UnquoteUsage(f)
+		fmt.Fprintf(w, "  --%s %s\n        %s\n", f.Name, kind, usage)
+	})
+}
+
+var registry = newCommandRegistry()
+
+func init() {
+	registry.register(&shellCommand{
+		name:        "chat",
+		usage:       "<message>",
+		description: "Send a chat message to the server",
+		minArgs:     1,
+		maxArgs:     -1,
+		rawArgs:     true,
+		run:         handleChatCommand,
+	})
+	registry.register(&shellCommand{
+		name:        "players",
+		aliases:     []string{"playerlist"},
+		description: "Get list of online players",
+		run:         handlePlayerListCommand,
+	})
+	registry.register(&shellCommand{
+		name:        "count",
+		aliases:     []string{"playercount"},
+		description: "Get number of online players",
+		run:         handlePlayerCountCommand,
+	})
+	registry.register(&shellCommand{
+		name:        "banlist",
+		description: "Get list of banned players, soonest to expire first",
+		run:         handleBanListCommand,
+	})
+	registry.register(&shellCommand{
+		name:        "kick",
+		usage:       "<player>",
+		description: "Kick a player by name, partial name or unique ID",
+		minArgs:     1,
+		maxArgs:     -1,
+		complete:    completeOnline,
+		run:         handleKickCommand,
+	})
+	registry.register(&shellCommand{
+		name:        "ban",
+		usage:       "<player> [length] [--hours <n> | --duration <length>] [--reason <text>]",
+		description: "Ban a player",
+		minArgs:     1,
+		maxArgs:     -1,
+		complete:    completeOnline,
+		flags: func(fs *flag.FlagSet) {
+			fs.Int("hours", 0, "ban length in hours")
+			fs.String("duration", "", "ban length such as 30m, 12h, 3d, 1w, 2d12h, or a date to ban until (2006-01-02 15:04)")
+			fs.String("reason", "", "reason shown to the player")
+		},
+		run: handleBanCommand,
+	})
+	registry.register(&shellCommand{
+		name:        "unban",
+		usage:       "<player>",
+		description: "Unban a player from the ban list",
+		minArgs:     1,
+		maxArgs:     -1,
+		complete:    completeBanned,
+		run:         handleUnbanCommand,
+	})
+	registry.register(&shellCommand{
+		name:        "whois",
+		usage:       "<player>",
+		description: "Look up online and banned players by name",
+		minArgs:     1,
+		maxArgs:     -1,
+		complete:    completeAnyPlayer,
+		run:         handleWhoisCommand,
+	})
+	registry.register(&shellCommand{
+		name:        "version",
+		description: "Get server version",
+		run:         handleVersionCommand,
+	})
+	registry.register(&shellCommand{
+		name:        "housing",
+		description: "Get housing list",
+		run:         handleHousingCommand,
+	})
+	registry.register(&shellCommand{
+		name:        "help",
+		usage:       "[command]",
+		description: "Show this help message, or the usage of one command",
+		maxArgs:     1,
+		complete:    completeCommand,
+		run:         handleHelpCommand,
+	})
+	registry.register(&shellCommand{
+		name:        "exit",
+		aliases:     []string{"quit", "disconnect"},
+		description: "Disconnect and return to main menu",
+		shellOnly:   true,
+		run: func(ctx context.Context, s *session, args commandArgs) error {
+			return errDisconnect
+		},
+	})
+}
+
+func handleHelpCommand(ctx context.Context, s *session, args commandArgs) error {
+	if len(args.words) == 0 {
+		registry.writeHelp(s.out)
+		return nil
+	}
+
+	cmd, ok := registry.lookup(args.words[0])
+	if !ok {
+		return exitcode.Usagef("unknown command: %s (type 'help' for available commands)", args.words[0])
+	}
+	registry.writeCommandHelp(s.out, cmd)
+	return nil
+}
@@ -0,0 +1,125 @@
+package connect
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"motor-town-server-tool/modules/exitcode"
+)
+
+// splitWords splits a shell line into words the way a POSIX shell would:
+// whitespace separates words, single quotes keep everything literally,
+// double quotes keep whitespace and honour \" and \\, and a backslash
+// outside quotes escapes the next character.
+func splitWords(line string) ([]string, error) {
+	scan := scanLine(line)
+
+	switch {
+	case scan.last.quote == '\'':
+		return nil, exitcode.Usagef("unterminated single quote")
+	case scan.last.quote == '"':
+		return nil, exitcode.Usagef("unterminated double quote")
+	case scan.escape:
+		return nil, exitcode.Usagef("unfinished escape at end of line")
+	}
+
+	if scan.open {
+		return append(scan.words, scan.last.value), nil
+	}
+	return scan.words, nil
+}
+
+// partialWord is the word at the end of a line, possibly still being typed.
+type partialWord struct {
+	start int    // byte offset of the word in the line
+	value string // the word as read so far, quotes and escapes removed
+	quote rune   // the quote left open at the end of the line, if any
+}
+
+type lineScan struct {
+	words  []string // the words finished before the last one
+	last   partialWord
+	open   bool // the line ends inside a word
+	escape bool // the line ends with an unfinished backslash escape
+}
+
+// scanLine reads line with the rules of splitWords without rejecting
+// unfinished input, so the word under the cursor can be completed.
+func scanLine(line string) lineScan {
+	var scan lineScan
+	var word strings.Builder
+
+	begin := func(i int) {
+		if !scan.open {
+			scan.open = true
+			scan.last.start = i
+		}
+	}
+
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+
+		switch {
+		case scan.last.quote == '\'':
+			if r == '\'' {
+				scan.last.quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+
+		case scan.last.quote == '"':
+			if r == '"' {
+				scan.last.quote = 0
+				break
+			}
+			if r == '\\' && i+1 < len(line) && (line[i+1] == '"' || line[i+1] == '\\') {
+				i++
+				r = rune(line[i])
+			}
+			word.WriteRune(r)
+
+		case r == ' ' || r == '\t':
+			if scan.open {
+				scan.words = append(scan.words, word.String())
+				word.Reset()
+				scan.open = false
+			}
+
+		case r == '\\':
+			begin(i)
+			if i+size == len(line) {
+				scan.escape = true
+				break
+			}
+			next, nextSize := utf8.DecodeRuneInString(line[i+size:])
+			word.WriteRune(next)
+			size += nextSize
+
+		case r == '\'' || r == '"':
+			begin(i)
+			scan.last.quote = r
+
+		default:
+			begin(i)
+			word.WriteRune(r)
+		}
+
+		i += size
+	}
+
+	if scan.open {
+		scan.last.value = word.String()
+	} else {
+		scan.last = partialWord{start: len(line)}
+	}
+	return scan
+}
+
+// quoteWord returns word in a form splitWords reads back as one word.
+func quoteWord(word string) string {
+	if word != "" && !strings.ContainsAny(word, " \t'\"\\") {
+		return word
+	}
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(word, "'", `'\''`))
+}
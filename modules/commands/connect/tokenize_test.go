@@ -0,0 +1,117 @@
+package connect
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"motor-town-server-tool/modules/exitcode"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{line: "", want: nil},
+		{line: "   \t ", want: nil},
+		{line: "kick alice", want: []string{"kick", "alice"}},
+		{line: "  kick \t alice  ", want: []string{"kick", "alice"}},
+		{line: `kick "John Smith"`, want: []string{"kick", "John Smith"}},
+		{line: `kick 'John  Smith'`, want: []string{"kick", "John  Smith"}},
+		{line: `kick John\ Smith`, want: []string{"kick", "John Smith"}},
+		{line: `whois O\'Brien`, want: []string{"whois", "O'Brien"}},
+		{line: `ban 1 --reason "don't   spawn-kill"`, want: []string{"ban", "1", "--reason", "don't   spawn-kill"}},
+		{line: `say "a \"quoted\" word"`, want: []string{"say", `a "quoted" word`}},
+		{line: `say "back\\slash"`, want: []string{"say", `back\slash`}},
+		{line: `say "keep \n as is"`, want: []string{"say", `keep \n as is`}},
+		{line: `say 'no \escapes\ here'`, want: []string{"say", `no \escapes\ here`}},
+		{line: `a"b c"'d e'f`, want: []string{"ab cd ef"}},
+		{line: `kick ""`, want: []string{"kick", ""}},
+		{line: `kick '' bob`, want: []string{"kick", "", "bob"}},
+		{line: `\\`, want: []string{`\`}},
+		{line: "whois Zoë", want: []string{"whois", "Zoë"}},
+		{line: `kick "alice`, wantErr: true},
+		{line: `kick 'alice`, wantErr: true},
+		{line: `kick "alice\"`, wantErr: true},
+		{line: `kick alice\`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := splitWords(tt.line)
+		if tt.wantErr {
+			var usageErr *exitcode.UsageError
+			if !errors.As(err, &usageErr) {
+				t.Errorf("splitWords(%q) error = %v, want a usage error", tt.line, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitWords(%q): %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitWords(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteWordRoundTrip(t *testing.T) {
+	for _, word := range []string{"alice", "", "John Smith", "O'Brien", `a "b" c`, `back\slash`, "tab\there", "'", "''"} {
+		got, err := splitWords(quoteWord(word))
+		if err != nil {
+			t.Errorf("splitWords(quoteWord(%q)): %v", word, err)
+			continue
+		}
+		if len(got) != 1 || got[0] != word {
+			t.Errorf("splitWords(quoteWord(%q)) = %q, want one word", word, got)
+		}
+	}
+}
+
+func TestParseBanArgs(t *testing.T) {
+	ban, _ := registry.lookup("ban")
+
+	tests := []struct {
+		words   []string
+		want    []string
+		reason  string
+		hours   int
+		wantErr bool
+	}{
+		{words: []string{"alice"}, want: []string{"alice"}},
+		{words: []string{"alice", "--hours", "3"}, want: []string{"alice"}, hours: 3},
+		{words: []string{"--reason", "spam", "alice", "12h"}, want: []string{"alice", "12h"}, reason: "spam"},
+		{words: []string{"alice", "--reason=spam", "2d"}, want: []string{"alice", "2d"}, reason: "spam"},
+		{words: []string{"--", "-dash"}, want: []string{"-dash"}},
+		{words: []string{"alice", "--", "--hours"}, want: []string{"alice", "--hours"}},
+		{words: []string{"alice", "--hours", "x"}, wantErr: true},
+		{words: []string{"alice", "--unknown"}, wantErr: true},
+		{words: []string{"--hours", "3"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		args, err := ban.parseArgs(tt.words)
+		if tt.wantErr {
+			var usageErr *exitcode.UsageError
+			if !errors.As(err, &usageErr) {
+				t.Errorf("parseArgs(%q) error = %v, want a usage error", tt.words, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseArgs(%q): %v", tt.words, err)
+			continue
+		}
+		if !reflect.DeepEqual(args.words, tt.want) {
+			t.Errorf("parseArgs(%q) words = %q, want %q", tt.words, args.words, tt.want)
+		}
+		if reason := args.value("reason").(string); reason != tt.reason {
+			t.Errorf("parseArgs(%q) reason = %q, want %q", tt.words, reason, tt.reason)
+		}
+		if hours := args.value("hours").(int); hours != tt.hours {
+			t.Errorf("parseArgs(%q) hours = %d, want %d", tt.words, hours, tt.hours)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package exec
+
+import (
+	"flag"
+	"strings"
+
+	"motor-town-server-tool/modules/commands/connect"
+	"motor-town-server-tool/modules/config"
+	"motor-town-server-tool/modules/exitcode"
+)
+
+type Command struct{}
+
+func (c *Command) Name() string {
+	return "exec"
+}
+
+func (c *Command) Description() string {
+	return "Run a single shell command against one or more instances"
+}
+
+func (c *Command) Execute(args []string) error {
+	flags := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	all := flags.Bool("all", false, "run against every configured instance")
+	parallel := flags.Int("parallel", connect.DefaultParallel, "maximum number of instances to contact concurrently")
+
+	var selector config.Selector
+	selector.BindFlags(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return exitcode.Usagef("%v", err)
+	}
+
+	rest := flags.Args()
+	target := connect.Target{All: *all, Selector: selector}
+
+	if !*all && selector.IsEmpty() {
+		if len(rest) < 1 {
+			return usageError()
+		}
+		target.Selector.Names = strings.Split(rest[0], ",")
+		rest = rest[1:]
+	}
+
+	if len(rest) < 1 {
+		return usageError()
+	}
+
+	return connect.Exec(target, rest, *parallel)
+}
+
+func usageError() error {
+	return exitcode.Usagef("usage: exec [--parallel <n>] (<instance[,instance...]> | --all | --tag <tag> | --group <group> | --region <region>) <command> [args...]")
+}
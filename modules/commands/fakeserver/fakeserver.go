@@ -0,0 +1,181 @@
+package fakeserver
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"motor-town-server-tool/modules/fakeserver"
+)
+
+type Command struct{}
+
+func (c *Command) Name() string {
+	return "fake-server"
+}
+
+func (c *Command) Description() string {
+	return "Run a local fake Motor Town web API for testing"
+}
+
+func (c *Command) Execute(args []string) error {
+	flags := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	listen := flags.String("listen", "127.0.0.1:8080", "address to listen on")
+	password := flags.String("password", "password", "web API password")
+	version := flags.String("version", fakeserver.DefaultVersion, "server version to report")
+	players := flags.String("players", "", "comma-separated name:unique_id pairs of online players")
+	houses := flags.String("houses", "", "comma-separated house:owner_unique_id pairs")
+	faultPath := flags.String("fault-path", "*", "endpoint the fault flags apply to")
+	latency := flags.String("latency", "", "delay added to each faulted response (e.g. 2s)")
+	status := flags.String("fail-status", "", "HTTP status returned by faulted responses (e.g. 500)")
+	malformed := flags.Bool("malformed", false, "return malformed JSON from faulted responses")
+	rate := flags.String("fault-rate", "", "probability between 0 and 1 that a request is faulted")
+	useTLS := flags.Bool("tls", false, "serve HTTPS (with a generated self-signed certificate unless --tls-cert is set)")
+	tlsCert := flags.String("tls-cert", "", "certificate file to serve HTTPS with")
+	tlsKey := flags.String("tls-key", "", "private key file for --tls-cert")
+	caOut := flags.String("tls-ca-out", "", "write the generated certificate here for use as an instance ca_file")
+	clientCA := flags.String("tls-client-ca", "", "require client certificates signed by this CA bundle")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	server := fakeserver.New(*password)
+	server.SetVersion(*version)
+
+	seedPlayers, err := parsePairs(*players)
+	if err != nil {
+		return fmt.Errorf("invalid --players: %w", err)
+	}
+	for _, pair := range seedPlayers {
+		server.AddPlayer(pair[0], pair[1])
+	}
+
+	seedHouses, err := parsePairs(*houses)
+	if err != nil {
+		return fmt.Errorf("invalid --houses: %w", err)
+	}
+	for _, pair := range seedHouses {
+		server.SetHouse(pair[0], fakeserver.House{
+			OwnerUniqueID: pair[1],
+			ExpireTime:    time.Now().Add(7 * 24 * time.Hour).UTC().Format(time.RFC3339),
+		})
+	}
+
+	if *latency != "" || *status != "" || *malformed || *rate != "" {
+		fault, err := fakeserver.ParseFault(*latency, *status, fmt.Sprint(*malformed), *rate, "")
+		if err != nil {
+			return err
+		}
+		server.InjectFault(*faultPath, fault)
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", *listen, err)
+	}
+
+	httpServer := &http.Server{Handler: server}
+
+	scheme := "http"
+	if *useTLS || *tlsCert != "" {
+		tlsConfig, err := serverTLSConfig(listener.Addr(), *tlsCert, *tlsKey, *caOut, *clientCA)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		httpServer.TLSConfig = tlsConfig
+		listener = tls.NewListener(listener, tlsConfig)
+		scheme = "https"
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Fake Motor Town web API listening on %s://%s (password: %s)\n", scheme, listener.Addr(), *password)
+	fmt.Println("Press Ctrl+C to stop.")
+
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("fake server failed: %w", err)
+	}
+
+	fmt.Println("Fake server stopped")
+	return nil
+}
+
+func serverTLSConfig(addr net.Addr, certFile, keyFile, caOut, clientCA string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load --tls-cert: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	} else {
+		hosts := []string{"localhost", "127.0.0.1", "::1"}
+		if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+			hosts = append(hosts, host)
+		}
+
+		cert, certPEM, err := fakeserver.GenerateCertificate(hosts)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+
+		if caOut != "" {
+			if err := os.WriteFile(caOut, certPEM, 0644); err != nil {
+				return nil, fmt.Errorf("failed to write --tls-ca-out: %w", err)
+			}
+			fmt.Printf("Wrote CA certificate to %s\n", caOut)
+		}
+	}
+
+	if clientCA != "" {
+		pem, err := os.ReadFile(clientCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --tls-client-ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", clientCA)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+func parsePairs(value string) ([][2]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var pairs [][2]string
+	for _, item := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok || key == "" || val == "" {
+			return nil, fmt.Errorf("expected key:value, got %q", item)
+		}
+		pairs = append(pairs, [2]string{key, val})
+	}
+	return pairs, nil
+}
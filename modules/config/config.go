@@ -1,9 +1,12 @@
 package config
 
 import (
+	"bytes"
+	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
+	"sort"
+	"strings"
 
 	"motor-town-server-tool/modules/types"
 
@@ -11,46 +14,110 @@ import (
 )
 
 type Config struct {
-	Instances map[string]types.Instance `toml:"instances"`
+	SchemaVersion int                       `toml:"schema_version"`
+	Encryption    *EncryptionConfig         `toml:"encryption,omitempty"`
+	Instances     map[string]types.Instance `toml:"instances"`
+
+	key          []byte
+	secretErrors map[string]error
 }
 
 func Load() (*Config, error) {
 	configPath := getConfigPath()
 
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
 		return &Config{
-			Instances: make(map[string]types.Instance),
+			SchemaVersion: CurrentSchemaVersion,
+			Instances:     make(map[string]types.Instance),
 		}, nil
 	}
-
-	var cfg Config
-	_, err := toml.DecodeFile(configPath, &cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
+	cfg, version, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config %s: %w", configPath, err)
+	}
+
 	if cfg.Instances == nil {
 		cfg.Instances = make(map[string]types.Instance)
 	}
 
-	return &cfg, nil
+	if version < CurrentSchemaVersion {
+		backup, err := upgradeFile(configPath, data, cfg, version)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not upgrade %s to schema version %d: %v\n", configPath, CurrentSchemaVersion, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Upgraded %s from schema version %d to %d (original kept as %s)\n", configPath, version, CurrentSchemaVersion, backup)
+		}
+	}
+
+	for name, instance := range cfg.Instances {
+		if err := ValidateInstance(name, instance); err != nil {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+	}
+
+	if err := cfg.decryptPasswords(); err != nil {
+		return nil, fmt.Errorf("failed to decrypt passwords: %w", err)
+	}
+
+	cfg.resolvePasswordSources()
+
+	return cfg, nil
+}
+
+// ValidateInstance applies the checks Load makes to every instance.
+func ValidateInstance(name string, instance types.Instance) error {
+	if err := validatePasswordSources(name, instance); err != nil {
+		return err
+	}
+	return validateTransport(name, instance)
+}
+
+func validateTransport(name string, instance types.Instance) error {
+	switch strings.ToLower(instance.Scheme) {
+	case "", "http", "https":
+	default:
+		return fmt.Errorf("instance '%s' has unsupported scheme %q (use http or https)", name, instance.Scheme)
+	}
+
+	if instance.Scheme != "" && instance.IsURL() && !strings.EqualFold(instance.Scheme, instance.URLScheme()) {
+		return fmt.Errorf("instance '%s' sets scheme %q but its URL uses %s", name, instance.Scheme, instance.URLScheme())
+	}
+
+	if instance.TLS != nil && instance.URLScheme() != "https" {
+		return fmt.Errorf("instance '%s' has [tls] settings but connects over %s", name, instance.URLScheme())
+	}
+
+	return nil
 }
 
 func (c *Config) Save() error {
 	configPath := getConfigPath()
 
-	file, err := os.Create(configPath)
+	out, err := c.persistedCopy()
 	if err != nil {
-		return fmt.Errorf("failed to create config file: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	encoder := toml.NewEncoder(file)
-	if err := encoder.Encode(c); err != nil {
+	var buf bytes.Buffer
+	encoder := toml.NewEncoder(&buf)
+	if err := encoder.Encode(out); err != nil {
 		return fmt.Errorf("failed to encode config: %w", err)
 	}
 
-	return nil
+	return withLock(func() error {
+		if err := writeAtomic(configPath, buf.Bytes()); err != nil {
+			return err
+		}
+		if out.Encryption != nil {
+			return secureBackups(configPath, out.Encryption, c.key)
+		}
+		return nil
+	})
 }
 
 func (c *Config) AddInstance(name string, instance types.Instance) {
@@ -88,12 +155,72 @@ func (c *Config) ListInstances() []string {
 	return names
 }
 
-func getConfigPath() string {
-	execPath, err := os.Executable()
-	if err != nil {
-		return "instances.toml"
+type Selector struct {
+	Names  []string
+	Tags   []string
+	Group  string
+	Region string
+}
+
+func (s Selector) IsEmpty() bool {
+	return len(s.Names) == 0 && len(s.Tags) == 0 && s.Group == "" && s.Region == ""
+}
+
+func (c *Config) Select(sel Selector) ([]string, error) {
+	candidates := c.ListInstances()
+
+	if len(sel.Names) > 0 {
+		candidates = candidates[:0]
+		seen := make(map[string]bool)
+		for _, name := range sel.Names {
+			name = strings.TrimSpace(name)
+			if name == "" || seen[name] {
+				continue
+			}
+			if _, exists := c.GetInstance(name); !exists {
+				return nil, fmt.Errorf("instance '%s' not found", name)
+			}
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
+	}
+
+	var selected []string
+	for _, name := range candidates {
+		instance, _ := c.GetInstance(name)
+		if sel.matches(instance) {
+			selected = append(selected, name)
+		}
+	}
+
+	sort.Strings(selected)
+	return selected, nil
+}
+
+func (s Selector) matches(instance types.Instance) bool {
+	if s.Group != "" && !strings.EqualFold(instance.Group, s.Group) {
+		return false
+	}
+	if s.Region != "" && !strings.EqualFold(instance.Region, s.Region) {
+		return false
 	}
+	for _, tag := range s.Tags {
+		if !instance.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
 
-	execDir := filepath.Dir(execPath)
-	return filepath.Join(execDir, "instances.toml")
+func (s *Selector) BindFlags(flags *flag.FlagSet) {
+	flags.Func("tag", "select instances with this tag (repeatable or comma-separated)", func(value string) error {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				s.Tags = append(s.Tags, tag)
+			}
+		}
+		return nil
+	})
+	flags.StringVar(&s.Group, "group", "", "select instances in this group")
+	flags.StringVar(&s.Region, "region", "", "select instances in this region")
 }
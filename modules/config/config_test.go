@@ -0,0 +1,57 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"motor-town-server-tool/modules/config"
+	"motor-town-server-tool/modules/types"
+)
+
+func TestSaveOmitsUnsetRetrySettings(t *testing.T) {
+	config.SetPath(filepath.Join(t.TempDir(), "instances.toml"))
+	t.Cleanup(func() { config.SetPath("") })
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Instances["post"] = types.Instance{IP: "127.0.0.1", Port: 8080, Password: "pw", Retry: &types.RetryConfig{RetryPOST: true}}
+	cfg.Instances["tuned"] = types.Instance{IP: "127.0.0.1", Port: 8081, Password: "pw", Retry: &types.RetryConfig{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+	}}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(config.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := string(data)
+	if strings.Contains(saved, `= "0s"`) || strings.Contains(saved, "= 0\n") {
+		t.Errorf("saved config writes unset retry settings:\n%s", saved)
+	}
+	for _, line := range []string{`max_attempts = 5`, `initial_backoff = "1s"`, `max_backoff = "10s"`} {
+		if !strings.Contains(saved, line) {
+			t.Errorf("saved config lacks %s:\n%s", line, saved)
+		}
+	}
+
+	reloaded, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, instance := range cfg.Instances {
+		got, _ := reloaded.GetInstance(name)
+		if !reflect.DeepEqual(got.Retry, instance.Retry) {
+			t.Errorf("instance %s reloaded retry = %+v, want %+v", name, got.Retry, instance.Retry)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+const (
+	ConfigEnv  = "MTST_CONFIG"
+	fileName   = "instances.toml"
+	appDirName = "mtst"
+)
+
+var explicitPath string
+
+func SetPath(path string) {
+	explicitPath = path
+}
+
+type Location struct {
+	Path   string
+	Source string
+	Exists bool
+}
+
+func Path() string {
+	return Locate().Path
+}
+
+// Locate resolves the configuration file in order of precedence: the
+// --config flag, MTST_CONFIG, then the first existing file in the user
+// config directory, the working directory and the executable's directory.
+// When none exists the user config directory is used for new files.
+func Locate() Location {
+	if explicitPath != "" {
+		return newLocation(explicitPath, "--config flag")
+	}
+
+	if path := os.Getenv(ConfigEnv); path != "" {
+		return newLocation(path, ConfigEnv+" environment variable")
+	}
+
+	candidates := searchPaths()
+	for _, candidate := range candidates {
+		if location := newLocation(candidate.Path, candidate.Source); location.Exists {
+			return location
+		}
+	}
+
+	return newLocation(candidates[0].Path, "default location")
+}
+
+func searchPaths() []Location {
+	var candidates []Location
+
+	if configDir, err := os.UserConfigDir(); err == nil {
+		candidates = append(candidates, Location{
+			Path:   filepath.Join(configDir, appDirName, fileName),
+			Source: "user config directory",
+		})
+	}
+
+	if workDir, err := os.Getwd(); err == nil {
+		candidates = append(candidates, Location{
+			Path:   filepath.Join(workDir, fileName),
+			Source: "current directory",
+		})
+	}
+
+	if execPath, err := os.Executable(); err == nil {
+		candidates = append(candidates, Location{
+			Path:   filepath.Join(filepath.Dir(execPath), fileName),
+			Source: "executable directory",
+		})
+	}
+
+	if len(candidates) == 0 {
+		candidates = append(candidates, Location{Path: fileName, Source: "current directory"})
+	}
+
+	return candidates
+}
+
+func newLocation(path, source string) Location {
+	if expanded, err := expandHome(path); err == nil {
+		path = expanded
+	}
+	if absolute, err := filepath.Abs(path); err == nil {
+		path = absolute
+	}
+
+	_, err := os.Stat(path)
+	return Location{Path: path, Source: source, Exists: err == nil}
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// HistoryPath returns the file the connect shell keeps the named instance's
+// command history in, under the user config directory.
+func HistoryPath(instance string) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	name := unsafeFileChars.ReplaceAllString(instance, "_") + ".history"
+	return filepath.Join(configDir, appDirName, "history", name), nil
+}
+
+func getConfigPath() string {
+	return Path()
+}
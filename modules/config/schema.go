@@ -0,0 +1,130 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// CurrentSchemaVersion is written to every saved file. Files without a
+// schema_version key predate versioning and are treated as version 1.
+const CurrentSchemaVersion = 2
+
+type migration struct {
+	from        int
+	description string
+	apply       func(doc map[string]interface{}) error
+}
+
+// migrations upgrade a raw document from version from to from+1. Add an
+// entry here, and bump CurrentSchemaVersion, whenever the file format
+// changes in a way older files need rewriting for.
+var migrations = []migration{
+	{
+		from:        1,
+		description: "record schema_version",
+		apply:       func(doc map[string]interface{}) error { return nil },
+	},
+}
+
+func schemaVersion(doc map[string]interface{}) (int, error) {
+	raw, ok := doc["schema_version"]
+	if !ok {
+		return 1, nil
+	}
+
+	version, ok := raw.(int64)
+	if !ok || version < 1 {
+		return 0, fmt.Errorf("schema_version must be a positive integer")
+	}
+	return int(version), nil
+}
+
+func migrate(doc map[string]interface{}, version int) error {
+	for ; version < CurrentSchemaVersion; version++ {
+		var step *migration
+		for i := range migrations {
+			if migrations[i].from == version {
+				step = &migrations[i]
+				break
+			}
+		}
+		if step == nil {
+			return fmt.Errorf("no migration from schema version %d", version)
+		}
+
+		if err := step.apply(doc); err != nil {
+			return fmt.Errorf("schema migration %d -> %d (%s) failed: %w", version, version+1, step.description, err)
+		}
+	}
+
+	doc["schema_version"] = int64(CurrentSchemaVersion)
+	return nil
+}
+
+// decode parses a configuration document, upgrading it to the current
+// schema and rejecting keys that do not map onto Config. It also returns
+// the schema version the document was written with.
+func decode(data []byte) (*Config, int, error) {
+	var doc map[string]interface{}
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		return nil, 0, err
+	}
+
+	version, err := schemaVersion(doc)
+	if err != nil {
+		return nil, 0, err
+	}
+	if version > CurrentSchemaVersion {
+		return nil, 0, fmt.Errorf("schema version %d is newer than this tool supports (%d); upgrade motor-town-server-tool", version, CurrentSchemaVersion)
+	}
+
+	if version < CurrentSchemaVersion {
+		if err := migrate(doc, version); err != nil {
+			return nil, 0, err
+		}
+
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(doc); err != nil {
+			return nil, 0, fmt.Errorf("failed to encode migrated config: %w", err)
+		}
+		data = buf.Bytes()
+	}
+
+	var cfg Config
+	meta, err := toml.Decode(string(data), &cfg)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+		return nil, 0, fmt.Errorf("unknown keys: %s", strings.Join(keys, ", "))
+	}
+
+	return &cfg, version, nil
+}
+
+// upgradeFile rewrites a configuration loaded from an older schema version,
+// keeping the original as <path>.v<version>.bak.
+func upgradeFile(path string, original []byte, cfg *Config, version int) (string, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(cfg); err != nil {
+		return "", fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", path, version)
+	err := withLock(func() error {
+		if err := os.WriteFile(backup, original, 0600); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+		return writeAtomic(path, buf.Bytes())
+	})
+	return backup, err
+}
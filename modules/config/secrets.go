@@ -0,0 +1,358 @@
+package config
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"motor-town-server-tool/modules/types"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+const (
+	encryptedPrefix = "enc:v1:"
+	checkPlaintext  = "motor-town-server-tool"
+	keySize         = 32
+	nonceSize       = 24
+
+	PassphraseEnv = "MTST_PASSPHRASE"
+	KeyFileEnv    = "MTST_KEY_FILE"
+)
+
+type EncryptionConfig struct {
+	Salt    string `toml:"salt,omitempty"`
+	KeyFile string `toml:"key_file,omitempty"`
+	Check   string `toml:"check"`
+}
+
+var ErrWrongKey = errors.New("incorrect master passphrase or key file")
+
+// PassphrasePrompt is used to ask for the master passphrase when it is not
+// provided through MTST_PASSPHRASE.
+var PassphrasePrompt = promptPassphrase
+
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+func (c *Config) EncryptionEnabled() bool {
+	return c.Encryption != nil
+}
+
+func (c *Config) EnablePassphraseEncryption(passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("passphrase cannot be empty")
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	return c.enableEncryption(&EncryptionConfig{Salt: base64.StdEncoding.EncodeToString(salt)}, key)
+}
+
+func (c *Config) EnableKeyFileEncryption(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := GenerateKeyFile(path); err != nil {
+			return err
+		}
+	}
+
+	key, err := readKeyFile(path)
+	if err != nil {
+		return err
+	}
+
+	return c.enableEncryption(&EncryptionConfig{KeyFile: path}, key)
+}
+
+func (c *Config) enableEncryption(enc *EncryptionConfig, key []byte) error {
+	check, err := encryptValue(key, checkPlaintext)
+	if err != nil {
+		return err
+	}
+	enc.Check = check
+
+	c.Encryption = enc
+	c.key = key
+	return nil
+}
+
+func GenerateKeyFile(path string) error {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(key) + "\n"
+	if err := os.WriteFile(path, []byte(encoded), 0600); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	return nil
+}
+
+func (c *Config) decryptPasswords() error {
+	hasEncrypted := false
+	for _, instance := range c.Instances {
+		if IsEncrypted(instance.Password) {
+			hasEncrypted = true
+			break
+		}
+	}
+
+	if c.Encryption == nil {
+		if hasEncrypted {
+			return fmt.Errorf("encrypted passwords found but the [encryption] section is missing")
+		}
+		return nil
+	}
+
+	key, err := c.Encryption.resolveKey()
+	if err != nil {
+		return err
+	}
+
+	return c.decryptWithKey(key)
+}
+
+func (c *Config) decryptWithKey(key []byte) error {
+	check, err := decryptValue(key, c.Encryption.Check)
+	if err != nil || check != checkPlaintext {
+		return ErrWrongKey
+	}
+	c.key = key
+
+	for name, instance := range c.Instances {
+		if !IsEncrypted(instance.Password) {
+			continue
+		}
+		password, err := decryptValue(key, instance.Password)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt password for instance '%s': %w", name, err)
+		}
+		instance.Password = password
+		c.Instances[name] = instance
+	}
+
+	return nil
+}
+
+func (c *Config) persistedCopy() (*Config, error) {
+	out := *c
+	out.SchemaVersion = CurrentSchemaVersion
+	out.Instances = make(map[string]types.Instance, len(c.Instances))
+
+	for name, instance := range c.Instances {
+		if instance.PasswordSource() != "" {
+			instance.Password = ""
+		} else if c.Encryption != nil && instance.Password != "" && !IsEncrypted(instance.Password) {
+			encrypted, err := encryptValue(c.key, instance.Password)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encrypt password for instance '%s': %w", name, err)
+			}
+			instance.Password = encrypted
+		}
+		out.Instances[name] = instance
+	}
+
+	return &out, nil
+}
+
+func (e *EncryptionConfig) resolveKey() ([]byte, error) {
+	keyFile := os.Getenv(KeyFileEnv)
+	if keyFile == "" {
+		keyFile = e.KeyFile
+	}
+	if keyFile != "" {
+		return readKeyFile(keyFile)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(e.Salt)
+	if err != nil || len(salt) == 0 {
+		return nil, fmt.Errorf("invalid encryption salt in configuration")
+	}
+
+	passphrase := os.Getenv(PassphraseEnv)
+	if passphrase == "" {
+		passphrase, err = PassphrasePrompt()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return deriveKey(passphrase, salt)
+}
+
+func readKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	if len(data) == keySize {
+		return data, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("key file %s must contain %d raw or base64-encoded bytes", path, keySize)
+	}
+	return key, nil
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return key, nil
+}
+
+func encryptValue(key []byte, plaintext string) (string, error) {
+	var secretKey [keySize]byte
+	copy(secretKey[:], key)
+
+	var nonce [nonceSize]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := secretbox.Seal(nonce[:], []byte(plaintext), &nonce, &secretKey)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptValue(key []byte, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", fmt.Errorf("value is not encrypted")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < nonceSize {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+
+	var secretKey [keySize]byte
+	copy(secretKey[:], key)
+
+	var nonce [nonceSize]byte
+	copy(nonce[:], sealed[:nonceSize])
+
+	plaintext, ok := secretbox.Open(nil, sealed[nonceSize:], &nonce, &secretKey)
+	if !ok {
+		return "", ErrWrongKey
+	}
+	return string(plaintext), nil
+}
+
+func promptPassphrase() (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("master passphrase required: set %s or %s", PassphraseEnv, KeyFileEnv)
+	}
+	return readPassphrase("Master passphrase: ")
+}
+
+func readPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(passphrase), nil
+}
+
+// secureBackups encrypts inline passwords left in plain text in the backups
+// of an encrypted configuration, so enabling encryption does not leave the
+// old secrets readable beside it or bring them back on restore. Backups
+// that cannot be re-encrypted with key are deleted instead.
+func secureBackups(path string, enc *EncryptionConfig, key []byte) error {
+	rotated, _ := filepath.Glob(path + ".bak.*")
+	versioned, _ := filepath.Glob(path + ".v*.bak")
+
+	for _, backup := range append(rotated, versioned...) {
+		data, err := os.ReadFile(backup)
+		if err != nil {
+			continue
+		}
+
+		secured, changed, err := encryptBackup(data, enc, key)
+		if err == nil && !changed {
+			continue
+		}
+		if err == nil {
+			err = os.WriteFile(backup, secured, 0600)
+			if err == nil {
+				continue
+			}
+		}
+
+		if removeErr := os.Remove(backup); removeErr != nil && !os.IsNotExist(removeErr) {
+			return fmt.Errorf("failed to remove backup %s holding plain-text passwords: %w", backup, removeErr)
+		}
+		fmt.Fprintf(os.Stderr, "Removed backup %s: it held plain-text passwords that could not be encrypted (%v)\n", backup, err)
+	}
+
+	return nil
+}
+
+func encryptBackup(data []byte, enc *EncryptionConfig, key []byte) ([]byte, bool, error) {
+	var doc map[string]interface{}
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		return nil, false, err
+	}
+
+	instances, _ := doc["instances"].(map[string]interface{})
+	changed := false
+	for _, raw := range instances {
+		instance, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		password, _ := instance["password"].(string)
+		if password == "" || IsEncrypted(password) {
+			continue
+		}
+
+		encrypted, err := encryptValue(key, password)
+		if err != nil {
+			return nil, false, err
+		}
+		instance["password"] = encrypted
+		changed = true
+	}
+	if !changed {
+		return data, false, nil
+	}
+
+	// Encrypted values elsewhere in the backup must share the new key, or
+	// the rewritten file could not be loaded.
+	if existing, ok := doc["encryption"].(map[string]interface{}); ok {
+		check, _ := existing["check"].(string)
+		if plaintext, err := decryptValue(key, check); err != nil || plaintext != checkPlaintext {
+			return nil, false, fmt.Errorf("backup is encrypted with a different key")
+		}
+	}
+	doc["encryption"] = enc
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(doc); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"motor-town-server-tool/modules/types"
+)
+
+const passwordCommandTimeout = 10 * time.Second
+
+func (c *Config) resolvePasswordSources() {
+	c.secretErrors = make(map[string]error)
+
+	for name, instance := range c.Instances {
+		if instance.PasswordSource() == "" {
+			continue
+		}
+
+		password, err := ResolvePassword(instance)
+		if err != nil {
+			c.secretErrors[name] = fmt.Errorf("instance '%s': %w", name, err)
+			continue
+		}
+
+		instance.Password = password
+		c.Instances[name] = instance
+	}
+}
+
+func (c *Config) ResolveInstance(name string) (types.Instance, error) {
+	instance, exists := c.GetInstance(name)
+	if !exists {
+		return instance, fmt.Errorf("instance '%s' not found", name)
+	}
+
+	if err := c.secretErrors[name]; err != nil {
+		return instance, err
+	}
+
+	instance, err := ResolveTLSFiles(instance)
+	if err != nil {
+		return instance, fmt.Errorf("instance '%s': %w", name, err)
+	}
+	return instance, nil
+}
+
+// ResolveTLSFiles returns instance with the files in its tls table resolved
+// like password_file, leaving the stored configuration as written.
+func ResolveTLSFiles(instance types.Instance) (types.Instance, error) {
+	if instance.TLS == nil {
+		return instance, nil
+	}
+
+	settings := *instance.TLS
+	for _, path := range []*string{&settings.CAFile, &settings.CertFile, &settings.KeyFile} {
+		if *path == "" {
+			continue
+		}
+		resolved, err := resolvePath(*path)
+		if err != nil {
+			return instance, err
+		}
+		*path = resolved
+	}
+
+	instance.TLS = &settings
+	return instance, nil
+}
+
+func validatePasswordSources(name string, instance types.Instance) error {
+	sources := 0
+	for _, value := range []string{instance.Password, instance.PasswordEnv, instance.PasswordFile, instance.PasswordCommand} {
+		if value != "" {
+			sources++
+		}
+	}
+
+	if sources > 1 {
+		return fmt.Errorf("instance '%s' must set only one of password, password_env, password_file or password_command", name)
+	}
+	return nil
+}
+
+// ResolvePassword reads the password from the instance's external source.
+func ResolvePassword(instance types.Instance) (string, error) {
+	switch {
+	case instance.PasswordEnv != "":
+		password, ok := os.LookupEnv(instance.PasswordEnv)
+		if !ok || password == "" {
+			return "", fmt.Errorf("password environment variable %s is not set", instance.PasswordEnv)
+		}
+		return password, nil
+
+	case instance.PasswordFile != "":
+		path, err := resolvePath(instance.PasswordFile)
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		// Only the first line is used, so the file can carry notes below it.
+		line, _, _ := strings.Cut(string(data), "\n")
+		password := strings.TrimRight(line, "\r")
+		if password == "" {
+			if len(bytes.TrimSpace(data)) == 0 {
+				return "", fmt.Errorf("password file %s is empty", path)
+			}
+			return "", fmt.Errorf("password file %s has an empty first line", path)
+		}
+		return password, nil
+
+	case instance.PasswordCommand != "":
+		return runPasswordCommand(instance.PasswordCommand)
+	}
+
+	return instance.Password, nil
+}
+
+func runPasswordCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Stdin = os.Stdin
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("password command timed out after %s", passwordCommandTimeout)
+		}
+		message := strings.TrimSpace(stderr.String())
+		if message != "" {
+			return "", fmt.Errorf("password command failed: %v: %s", err, message)
+		}
+		return "", fmt.Errorf("password command failed: %w", err)
+	}
+
+	password := strings.TrimRight(stdout.String(), "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("password command produced no output")
+	}
+	return password, nil
+}
+
+// resolvePath resolves a file named in the configuration. A leading ~ is
+// the home directory and a relative path is taken from the directory of the
+// configuration file, so it does not depend on where mtst is run from.
+func resolvePath(path string) (string, error) {
+	path, err := expandHome(path)
+	if err != nil {
+		return "", err
+	}
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	return filepath.Join(filepath.Dir(getConfigPath()), path), nil
+}
+
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to expand %s: %w", path, err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
@@ -0,0 +1,120 @@
+package config_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"motor-town-server-tool/modules/config"
+	"motor-town-server-tool/modules/types"
+)
+
+func TestResolvePasswordFile(t *testing.T) {
+	configDir, home, workDir := t.TempDir(), t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	chdir(t, workDir)
+	config.SetPath(filepath.Join(configDir, "instances.toml"))
+	t.Cleanup(func() { config.SetPath("") })
+
+	writeFile(t, filepath.Join(configDir, "secrets", "pw"), "beside-config\nnotes\n")
+	writeFile(t, filepath.Join(home, "pw"), "in-home\n")
+	writeFile(t, filepath.Join(workDir, "secrets", "pw"), "in-workdir\n")
+	writeFile(t, filepath.Join(configDir, "empty"), "\n\n")
+	writeFile(t, filepath.Join(configDir, "blank-first"), "\nsecret\n")
+
+	tests := []struct {
+		file    string
+		want    string
+		wantErr bool
+	}{
+		{file: "secrets/pw", want: "beside-config"},
+		{file: "./secrets/pw", want: "beside-config"},
+		{file: filepath.Join(workDir, "secrets", "pw"), want: "in-workdir"},
+		{file: "~/pw", want: "in-home"},
+		{file: "missing", wantErr: true},
+		{file: "empty", wantErr: true},
+		{file: "blank-first", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := config.ResolvePassword(types.Instance{PasswordFile: tt.file})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("password_file %q = %q, want an error", tt.file, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("password_file %q: %v", tt.file, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("password_file %q = %q, want %q", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestResolveInstanceTLSFiles(t *testing.T) {
+	configDir, home := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	config.SetPath(filepath.Join(configDir, "instances.toml"))
+	t.Cleanup(func() { config.SetPath("") })
+
+	writeFile(t, config.Path(), fmt.Sprintf(`schema_version = %d
+
+[instances.prod]
+ip = "mt.example.org"
+port = 443
+
+[instances.prod.tls]
+ca_file = "certs/ca.pem"
+cert_file = "~/client.pem"
+key_file = "/etc/mtst/client.key"
+`, config.CurrentSchemaVersion))
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instance, err := cfg.ResolveInstance("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := types.TLSConfig{
+		CAFile:   filepath.Join(configDir, "certs", "ca.pem"),
+		CertFile: filepath.Join(home, "client.pem"),
+		KeyFile:  "/etc/mtst/client.key",
+	}
+	if *instance.TLS != want {
+		t.Errorf("resolved tls = %+v, want %+v", *instance.TLS, want)
+	}
+
+	stored, _ := cfg.GetInstance("prod")
+	if stored.TLS.CAFile != "certs/ca.pem" || stored.TLS.CertFile != "~/client.pem" {
+		t.Errorf("resolving changed the stored tls table: %+v", *stored.TLS)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+const (
+	MaxBackups  = 5
+	lockTimeout = 5 * time.Second
+)
+
+var heldLock *flock.Flock
+
+type Backup struct {
+	Path    string
+	Index   int
+	ModTime time.Time
+}
+
+// Lock takes the exclusive configuration lock for the rest of a
+// read-modify-write session such as configure. Saves made while it is held
+// reuse it instead of locking again.
+func Lock() (func(), error) {
+	if heldLock != nil {
+		return func() {}, nil
+	}
+
+	lock, err := acquireLock()
+	if err != nil {
+		return nil, err
+	}
+
+	heldLock = lock
+	return func() {
+		heldLock = nil
+		lock.Unlock()
+	}, nil
+}
+
+func withLock(fn func() error) error {
+	if heldLock != nil {
+		return fn()
+	}
+
+	lock, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return fn()
+}
+
+func acquireLock() (*flock.Flock, error) {
+	configPath := getConfigPath()
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	lock := flock.New(configPath + ".lock")
+
+	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+	defer cancel()
+
+	locked, err := lock.TryLockContext(ctx, 100*time.Millisecond)
+	if err != nil && ctx.Err() == nil {
+		return nil, fmt.Errorf("failed to lock configuration: %w", err)
+	}
+	if !locked {
+		return nil, fmt.Errorf("configuration is locked by another process (%s)", lock.Path())
+	}
+
+	return lock, nil
+}
+
+func writeAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary config file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close config file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		return fmt.Errorf("failed to set config file permissions: %w", err)
+	}
+
+	if err := rotateBackups(path); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace config file: %w", err)
+	}
+
+	return nil
+}
+
+func backupPath(path string, index int) string {
+	return fmt.Sprintf("%s.bak.%d", path, index)
+}
+
+func rotateBackups(path string) error {
+	current, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config for backup: %w", err)
+	}
+
+	os.Remove(backupPath(path, MaxBackups))
+	for i := MaxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupPath(path, i), backupPath(path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate backups: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(backupPath(path, 1), current, 0600); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+func ListBackups() ([]Backup, error) {
+	configPath := getConfigPath()
+
+	matches, err := filepath.Glob(configPath + ".bak.*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var backups []Backup
+	for _, match := range matches {
+		index, err := strconv.Atoi(strings.TrimPrefix(match, configPath+".bak."))
+		if err != nil {
+			continue
+		}
+
+		info, err := os.Stat(match)
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, Backup{Path: match, Index: index, ModTime: info.ModTime()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Index < backups[j].Index
+	})
+	return backups, nil
+}
+
+func RestoreBackup(backup Backup) error {
+	data, err := os.ReadFile(backup.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	return withLock(func() error {
+		return writeAtomic(getConfigPath(), data)
+	})
+}
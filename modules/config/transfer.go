@@ -0,0 +1,351 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"motor-town-server-tool/modules/types"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/term"
+)
+
+const ExportPassphraseEnv = "MTST_EXPORT_PASSPHRASE"
+
+type PasswordMode string
+
+const (
+	PasswordsRedact  PasswordMode = "redact"
+	PasswordsEncrypt PasswordMode = "encrypt"
+	PasswordsPlain   PasswordMode = "plain"
+)
+
+func ParsePasswordMode(value string) (PasswordMode, error) {
+	switch mode := PasswordMode(strings.ToLower(value)); mode {
+	case PasswordsRedact, PasswordsEncrypt, PasswordsPlain:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported password mode %q (expected redact, encrypt or plain)", value)
+	}
+}
+
+type FileFormat string
+
+const (
+	FormatTOML FileFormat = "toml"
+	FormatJSON FileFormat = "json"
+)
+
+// ParseFileFormat returns the named format or, when value is empty, the
+// format implied by the extension of path (TOML unless it ends in .json).
+func ParseFileFormat(value, path string) (FileFormat, error) {
+	if value == "" {
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			return FormatJSON, nil
+		}
+		return FormatTOML, nil
+	}
+
+	switch format := FileFormat(strings.ToLower(value)); format {
+	case FormatTOML, FormatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported file format %q (expected toml or json)", value)
+	}
+}
+
+type Strategy string
+
+const (
+	StrategySkip      Strategy = "skip"
+	StrategyOverwrite Strategy = "overwrite"
+	StrategyRename    Strategy = "rename"
+)
+
+func ParseStrategy(value string) (Strategy, error) {
+	switch strategy := Strategy(strings.ToLower(value)); strategy {
+	case StrategySkip, StrategyOverwrite, StrategyRename:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unsupported strategy %q (expected skip, overwrite or rename)", value)
+	}
+}
+
+// ExportPassphrase returns the passphrase protecting an encrypted export,
+// from MTST_EXPORT_PASSPHRASE or, on a terminal, by prompting. With confirm
+// set the passphrase has to be entered twice.
+func ExportPassphrase(prompt string, confirm bool) (string, error) {
+	if passphrase := os.Getenv(ExportPassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("export passphrase required: set %s", ExportPassphraseEnv)
+	}
+
+	passphrase, err := readPassphrase(prompt)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase cannot be empty")
+	}
+
+	if confirm {
+		again, err := readPassphrase("Confirm passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+
+	return passphrase, nil
+}
+
+// Export returns a standalone configuration holding the named instances,
+// ready to be marshalled. Inline passwords are blanked, encrypted with
+// passphrase or kept as plain text depending on mode.
+func (c *Config) Export(names []string, mode PasswordMode, passphrase string) (*Config, error) {
+	out := &Config{Instances: make(map[string]types.Instance, len(names))}
+
+	for _, name := range names {
+		instance, exists := c.GetInstance(name)
+		if !exists {
+			return nil, fmt.Errorf("instance '%s' not found", name)
+		}
+		if mode == PasswordsRedact && instance.PasswordSource() == "" {
+			instance.Password = ""
+		}
+		out.Instances[name] = instance
+	}
+
+	if mode == PasswordsEncrypt {
+		if err := out.EnablePassphraseEncryption(passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	return out.persistedCopy()
+}
+
+func Marshal(cfg *Config, format FileFormat) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if format == FormatTOML {
+		return buf.Bytes(), nil
+	}
+
+	// JSON mirrors the TOML document so both share key names and formats.
+	var doc map[string]interface{}
+	if _, err := toml.Decode(buf.String(), &doc); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+func Unmarshal(data []byte, format FileFormat) (*Config, error) {
+	if format == FormatJSON {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+
+		var doc map[string]interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %w", err)
+		}
+
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(normalizeJSON(doc)); err != nil {
+			return nil, fmt.Errorf("failed to convert JSON: %w", err)
+		}
+		data = buf.Bytes()
+	}
+
+	cfg, _, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if cfg.Instances == nil {
+		cfg.Instances = make(map[string]types.Instance)
+	}
+
+	return cfg, nil
+}
+
+func normalizeJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeJSON(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeJSON(item)
+		}
+		return v
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	default:
+		return v
+	}
+}
+
+// ReadImport loads an exported file, validating its instances and
+// decrypting passwords with the passphrase returned by passphrase.
+func ReadImport(path string, format FileFormat, passphrase func() (string, error)) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	cfg, err := Unmarshal(data, format)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, instance := range cfg.Instances {
+		if err := validateImported(name, instance); err != nil {
+			return nil, fmt.Errorf("invalid instance in %s: %w", path, err)
+		}
+	}
+
+	if cfg.Encryption == nil {
+		for name, instance := range cfg.Instances {
+			if IsEncrypted(instance.Password) {
+				return nil, fmt.Errorf("instance '%s' has an encrypted password but %s has no [encryption] section", name, path)
+			}
+		}
+		return cfg, nil
+	}
+
+	var key []byte
+	if cfg.Encryption.KeyFile != "" {
+		key, err = readKeyFile(cfg.Encryption.KeyFile)
+	} else {
+		key, err = cfg.Encryption.passphraseKey(passphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.decryptWithKey(key); err != nil {
+		if errors.Is(err, ErrWrongKey) {
+			return nil, fmt.Errorf("incorrect passphrase for %s", path)
+		}
+		return nil, err
+	}
+	cfg.Encryption = nil
+	cfg.key = nil
+
+	return cfg, nil
+}
+
+// validateImported applies the checks configure makes on the way in, so an
+// imported instance could also have been added by hand.
+func validateImported(name string, instance types.Instance) error {
+	if err := ValidateInstanceName(name); err != nil {
+		return fmt.Errorf("instance '%s': %w", name, err)
+	}
+	if err := ValidateAddress(instance.IP); err != nil {
+		return fmt.Errorf("instance '%s': %w", name, err)
+	}
+	if !instance.IsURL() {
+		if err := ValidatePort(strconv.Itoa(instance.Port)); err != nil {
+			return fmt.Errorf("instance '%s': %w", name, err)
+		}
+	}
+	return ValidateInstance(name, instance)
+}
+
+func (e *EncryptionConfig) passphraseKey(passphrase func() (string, error)) ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(e.Salt)
+	if err != nil || len(salt) == 0 {
+		return nil, fmt.Errorf("invalid encryption salt")
+	}
+
+	value, err := passphrase()
+	if err != nil {
+		return nil, err
+	}
+	return deriveKey(value, salt)
+}
+
+type ImportResult struct {
+	Name    string
+	SavedAs string
+	Action  string
+	Warning string
+}
+
+// Import merges the instances of src into c, resolving name conflicts with
+// strategy. Results are returned in name order.
+func (c *Config) Import(src *Config, strategy Strategy) []ImportResult {
+	names := src.ListInstances()
+	sort.Strings(names)
+
+	results := make([]ImportResult, 0, len(names))
+	for _, name := range names {
+		instance, _ := src.GetInstance(name)
+		result := ImportResult{Name: name, SavedAs: name, Action: "added"}
+
+		if _, exists := c.GetInstance(name); exists {
+			switch strategy {
+			case StrategyOverwrite:
+				result.Action = "overwritten"
+			case StrategyRename:
+				result.SavedAs = c.unusedName(name)
+				result.Action = "renamed"
+			default:
+				result.SavedAs = ""
+				result.Action = "skipped"
+				results = append(results, result)
+				continue
+			}
+		}
+
+		if instance.Password == "" && instance.PasswordSource() == "" {
+			result.Warning = "no password; set one with 'configure edit'"
+		}
+
+		c.AddInstance(result.SavedAs, instance)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// unusedName appends the first free "-N" to name, shortening name when
+// needed to keep the result a valid instance name.
+func (c *Config) unusedName(name string) string {
+	for i := 2; ; i++ {
+		suffix := fmt.Sprintf("-%d", i)
+		base := name
+		if len(base)+len(suffix) > MaxInstanceNameLength {
+			base = base[:MaxInstanceNameLength-len(suffix)]
+		}
+		candidate := base + suffix
+		if _, exists := c.GetInstance(candidate); !exists {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxInstanceNameLength is the longest instance name accepted.
+const MaxInstanceNameLength = 72
+
+var validInstanceName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+func ValidateInstanceName(name string) error {
+	if name == "" {
+		return fmt.Errorf("instance name cannot be empty")
+	}
+	if len(name) > MaxInstanceNameLength {
+		return fmt.Errorf("instance name cannot exceed %d characters", MaxInstanceNameLength)
+	}
+
+	if !validInstanceName.MatchString(name) {
+		return fmt.Errorf("instance name can only contain lowercase letters (a-z), numbers (0-9), hyphens (-), and underscores (_)")
+	}
+
+	return nil
+}
+
+func ValidateAddress(address string) error {
+	if address == "" {
+		return fmt.Errorf("address cannot be empty")
+	}
+	if strings.ContainsAny(address, " \t") {
+		return fmt.Errorf("address cannot contain whitespace")
+	}
+
+	if strings.Contains(address, "://") {
+		return validateBaseURL(address)
+	}
+
+	if strings.HasPrefix(address, "[") {
+		if !strings.HasSuffix(address, "]") {
+			return fmt.Errorf("bracketed IPv6 address is missing ']'")
+		}
+		if ip := net.ParseIP(address[1 : len(address)-1]); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("only IPv6 addresses can be written in brackets")
+		}
+		return nil
+	}
+
+	return validateHost(address)
+}
+
+func validateBaseURL(address string) error {
+	u, err := url.Parse(address)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("URL scheme must be http or https")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("URL must include a host")
+	}
+	if u.User != nil {
+		return fmt.Errorf("URL cannot contain credentials; set the password separately")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("URL cannot contain a query or fragment")
+	}
+
+	if port := u.Port(); port != "" {
+		if err := ValidatePort(port); err != nil {
+			return err
+		}
+	}
+
+	return validateHost(u.Hostname())
+}
+
+// validateHost checks that host is an IPv4 address, an IPv6 address or a
+// syntactically valid hostname. No DNS lookups are made.
+func validateHost(host string) error {
+	if host == "" {
+		return fmt.Errorf("host cannot be empty")
+	}
+
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	if strings.Contains(host, ":") {
+		if _, _, err := net.SplitHostPort(host); err == nil {
+			return fmt.Errorf("enter the port separately, or use a URL")
+		}
+		return fmt.Errorf("invalid IPv6 address: %s", host)
+	}
+
+	host = strings.TrimSuffix(host, ".")
+	if len(host) > 253 {
+		return fmt.Errorf("hostname cannot exceed 253 characters")
+	}
+
+	validLabel := regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+	labels := strings.Split(host, ".")
+	for _, label := range labels {
+		if !validLabel.MatchString(label) {
+			return fmt.Errorf("invalid hostname label %q", label)
+		}
+	}
+
+	if _, err := strconv.Atoi(labels[len(labels)-1]); err == nil {
+		return fmt.Errorf("invalid IP address: %s", host)
+	}
+
+	return nil
+}
+
+func ValidatePort(portStr string) error {
+	if portStr == "" {
+		return fmt.Errorf("port cannot be empty")
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("port must be a number: %s", portStr)
+	}
+
+	if port < 0 || port > 65535 {
+		return fmt.Errorf("port must be between 0 and 65535, got: %d", port)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package exitcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"motor-town-server-tool/modules/api"
+)
+
+const (
+	OK           = 0
+	Failure      = 1
+	Usage        = 2
+	Unauthorized = 3
+	Unreachable  = 4
+	ServerError  = 5
+	Certificate  = 6
+	Interrupted  = 130
+)
+
+type UsageError struct {
+	Message string
+}
+
+func (e *UsageError) Error() string {
+	return e.Message
+}
+
+func Usagef(format string, args ...interface{}) error {
+	return &UsageError{Message: fmt.Sprintf(format, args...)}
+}
+
+type Error struct {
+	Code int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func FromError(err error) int {
+	if err == nil {
+		return OK
+	}
+
+	var exitErr *Error
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	var usageErr *UsageError
+	var httpErr *api.HTTPError
+	var apiErr *api.APIError
+	var decodeErr *api.DecodeError
+
+	switch {
+	case errors.As(err, &usageErr):
+		return Usage
+	case errors.Is(err, context.Canceled):
+		return Interrupted
+	case errors.Is(err, api.ErrUnauthorized):
+		return Unauthorized
+	case errors.Is(err, api.ErrCertificate):
+		return Certificate
+	case errors.Is(err, api.ErrUnreachable), errors.Is(err, context.DeadlineExceeded):
+		return Unreachable
+	case errors.As(err, &httpErr), errors.As(err, &apiErr), errors.As(err, &decodeErr):
+		return ServerError
+	default:
+		return Failure
+	}
+}
+
+// Combine returns the exit code for a batch of per-instance errors: OK when
+// all succeeded, the shared code when every failure agrees, else Failure.
+func Combine(errs []error) int {
+	code := OK
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		errCode := FromError(err)
+		if code == OK {
+			code = errCode
+		} else if code != errCode {
+			code = Failure
+		}
+	}
+	return code
+}
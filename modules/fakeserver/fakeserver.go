@@ -0,0 +1,465 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const DefaultVersion = "fake-0.0.0"
+
+type Player struct {
+	Name     string `json:"name"`
+	UniqueID string `json:"unique_id"`
+
+	// Set on bans only. ExpireTime is RFC 3339 and empty for permanent bans.
+	Reason     string `json:"reason,omitempty"`
+	ExpireTime string `json:"expire_time,omitempty"`
+}
+
+type House struct {
+	OwnerUniqueID string `json:"owner_unique_id"`
+	ExpireTime    string `json:"expire_time"`
+}
+
+type Fault struct {
+	Latency    time.Duration
+	StatusCode int
+	Malformed  bool
+	Rate       float64
+	Count      int
+}
+
+// Server is an in-memory stand-in for the Motor Town dedicated server web
+// API. It implements http.Handler so it can be mounted on an httptest.Server
+// or served directly by the fake-server command.
+type Server struct {
+	mu       sync.Mutex
+	password string
+	version  string
+	players  []Player
+	bans     []Player
+	housing  map[string]House
+	chat     []string
+	faults   map[string]*Fault
+	mux      *http.ServeMux
+}
+
+func New(password string) *Server {
+	s := &Server{
+		password: password,
+		version:  DefaultVersion,
+		housing:  make(map[string]House),
+		faults:   make(map[string]*Fault),
+		mux:      http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/chat", s.post(s.handleChat))
+	s.mux.HandleFunc("/player/count", s.get(s.handlePlayerCount))
+	s.mux.HandleFunc("/player/list", s.get(s.handlePlayerList))
+	s.mux.HandleFunc("/player/banlist", s.get(s.handleBanList))
+	s.mux.HandleFunc("/player/kick", s.post(s.handleKick))
+	s.mux.HandleFunc("/player/ban", s.post(s.handleBan))
+	s.mux.HandleFunc("/player/unban", s.post(s.handleUnban))
+	s.mux.HandleFunc("/version", s.get(s.handleVersion))
+	s.mux.HandleFunc("/housing/list", s.get(s.handleHousingList))
+
+	s.mux.HandleFunc("/_fake/fault", s.handleFaultControl)
+	s.mux.HandleFunc("/_fake/join", s.handleJoinControl)
+	s.mux.HandleFunc("/_fake/leave", s.handleLeaveControl)
+	s.mux.HandleFunc("/_fake/chatlog", s.handleChatLogControl)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) SetVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+}
+
+func (s *Server) AddPlayer(name, uniqueID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.players = removePlayer(s.players, uniqueID)
+	s.players = append(s.players, Player{Name: name, UniqueID: uniqueID})
+}
+
+func (s *Server) RemovePlayer(uniqueID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before := len(s.players)
+	s.players = removePlayer(s.players, uniqueID)
+	return len(s.players) != before
+}
+
+func (s *Server) Players() []Player {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Player(nil), s.players...)
+}
+
+func (s *Server) Bans() []Player {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneBans()
+	return append([]Player(nil), s.bans...)
+}
+
+// pruneBans drops bans that have expired. Callers must hold s.mu.
+func (s *Server) pruneBans() {
+	now := time.Now()
+	kept := s.bans[:0]
+	for _, ban := range s.bans {
+		if expires, err := time.Parse(time.RFC3339, ban.ExpireTime); err == nil && !expires.After(now) {
+			continue
+		}
+		kept = append(kept, ban)
+	}
+	s.bans = kept
+}
+
+func (s *Server) SetHouse(name string, house House) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.housing[name] = house
+}
+
+func (s *Server) ChatLog() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.chat...)
+}
+
+// InjectFault applies fault to requests for path, or to every API request
+// when path is "*". A zero Count keeps the fault active until cleared.
+func (s *Server) InjectFault(path string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[path] = &fault
+}
+
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = make(map[string]*Fault)
+}
+
+func (s *Server) get(handler http.HandlerFunc) http.HandlerFunc {
+	return s.endpoint(http.MethodGet, handler)
+}
+
+func (s *Server) post(handler http.HandlerFunc) http.HandlerFunc {
+	return s.endpoint(http.MethodPost, handler)
+}
+
+func (s *Server) endpoint(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if fault, ok := s.takeFault(r.URL.Path); ok {
+			if fault.Latency > 0 {
+				select {
+				case <-time.After(fault.Latency):
+				case <-r.Context().Done():
+					return
+				}
+			}
+			if fault.StatusCode != 0 {
+				writeJSON(w, fault.StatusCode, nil, http.StatusText(fault.StatusCode), false)
+				return
+			}
+			if fault.Malformed {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, `{"data": {"truncated`)
+				return
+			}
+		}
+
+		if r.Method != method {
+			writeJSON(w, http.StatusMethodNotAllowed, nil, "Method not allowed", false)
+			return
+		}
+
+		if r.URL.Query().Get("password") != s.password {
+			writeJSON(w, http.StatusUnauthorized, nil, "Invalid password", false)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+func (s *Server) takeFault(path string) (Fault, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := path
+	fault, ok := s.faults[key]
+	if !ok {
+		key = "*"
+		fault, ok = s.faults[key]
+	}
+	if !ok {
+		return Fault{}, false
+	}
+
+	if fault.Rate > 0 && rand.Float64() >= fault.Rate {
+		return Fault{}, false
+	}
+
+	if fault.Count > 0 {
+		fault.Count--
+		if fault.Count == 0 {
+			delete(s.faults, key)
+		}
+	}
+
+	return *fault, true
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	message := r.URL.Query().Get("message")
+	if message == "" {
+		writeJSON(w, http.StatusBadRequest, nil, "message is required", false)
+		return
+	}
+
+	s.mu.Lock()
+	s.chat = append(s.chat, message)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, nil, "Message sent", true)
+}
+
+func (s *Server) handlePlayerCount(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	count := len(s.players)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]int{"num_players": count}, "", true)
+}
+
+func (s *Server) handlePlayerList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, indexed(s.Players()), "", true)
+}
+
+func (s *Server) handleBanList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, indexed(s.Bans()), "", true)
+}
+
+func (s *Server) handleKick(w http.ResponseWriter, r *http.Request) {
+	uniqueID := r.URL.Query().Get("unique_id")
+	if uniqueID == "" {
+		writeJSON(w, http.StatusBadRequest, nil, "unique_id is required", false)
+		return
+	}
+
+	if !s.RemovePlayer(uniqueID) {
+		writeJSON(w, http.StatusOK, nil, "Player not found", false)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nil, "Player kicked", true)
+}
+
+func (s *Server) handleBan(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	uniqueID := query.Get("unique_id")
+	if uniqueID == "" {
+		writeJSON(w, http.StatusBadRequest, nil, "unique_id is required", false)
+		return
+	}
+
+	ban := Player{UniqueID: uniqueID, Reason: query.Get("reason")}
+	if hours := query.Get("hours"); hours != "" {
+		n, err := strconv.Atoi(hours)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, nil, "hours must be an integer", false)
+			return
+		}
+		if n > 0 {
+			ban.ExpireTime = time.Now().Add(time.Duration(n) * time.Hour).UTC().Format(time.RFC3339)
+		}
+	}
+
+	s.mu.Lock()
+	for _, player := range append(s.bans, s.players...) {
+		if player.UniqueID == uniqueID {
+			ban.Name = player.Name
+		}
+	}
+	s.players = removePlayer(s.players, uniqueID)
+	s.bans = removePlayer(s.bans, uniqueID)
+	s.bans = append(s.bans, ban)
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, nil, "Player banned", true)
+}
+
+func (s *Server) handleUnban(w http.ResponseWriter, r *http.Request) {
+	uniqueID := r.URL.Query().Get("unique_id")
+	if uniqueID == "" {
+		writeJSON(w, http.StatusBadRequest, nil, "unique_id is required", false)
+		return
+	}
+
+	s.mu.Lock()
+	s.pruneBans()
+	before := len(s.bans)
+	s.bans = removePlayer(s.bans, uniqueID)
+	found := len(s.bans) != before
+	s.mu.Unlock()
+
+	if !found {
+		writeJSON(w, http.StatusOK, nil, "Player is not banned", false)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nil, "Player unbanned", true)
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	version := s.version
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]string{"version": version}, "", true)
+}
+
+func (s *Server) handleHousingList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	housing := make(map[string]House, len(s.housing))
+	for name, house := range s.housing {
+		housing[name] = house
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, housing, "", true)
+}
+
+func (s *Server) handleFaultControl(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	switch r.Method {
+	case http.MethodDelete:
+		s.ClearFaults()
+		writeJSON(w, http.StatusOK, nil, "Faults cleared", true)
+		return
+	case http.MethodPost:
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, nil, "Method not allowed", false)
+		return
+	}
+
+	fault, err := ParseFault(query.Get("latency"), query.Get("status"), query.Get("malformed"), query.Get("rate"), query.Get("count"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, nil, err.Error(), false)
+		return
+	}
+
+	path := query.Get("path")
+	if path == "" {
+		path = "*"
+	}
+	s.InjectFault(path, fault)
+
+	writeJSON(w, http.StatusOK, nil, fmt.Sprintf("Fault injected for %s", path), true)
+}
+
+func (s *Server) handleJoinControl(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if r.Method != http.MethodPost || query.Get("unique_id") == "" {
+		writeJSON(w, http.StatusBadRequest, nil, "POST with name and unique_id is required", false)
+		return
+	}
+
+	s.AddPlayer(query.Get("name"), query.Get("unique_id"))
+	writeJSON(w, http.StatusOK, nil, "Player joined", true)
+}
+
+func (s *Server) handleLeaveControl(w http.ResponseWriter, r *http.Request) {
+	uniqueID := r.URL.Query().Get("unique_id")
+	if r.Method != http.MethodPost || uniqueID == "" {
+		writeJSON(w, http.StatusBadRequest, nil, "POST with unique_id is required", false)
+		return
+	}
+
+	if !s.RemovePlayer(uniqueID) {
+		writeJSON(w, http.StatusOK, nil, "Player not found", false)
+		return
+	}
+	writeJSON(w, http.StatusOK, nil, "Player left", true)
+}
+
+func (s *Server) handleChatLogControl(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.ChatLog(), "", true)
+}
+
+func ParseFault(latency, status, malformed, rate, count string) (Fault, error) {
+	var fault Fault
+	var err error
+
+	if latency != "" {
+		if fault.Latency, err = time.ParseDuration(latency); err != nil {
+			return fault, fmt.Errorf("invalid latency %q: %w", latency, err)
+		}
+	}
+	if status != "" {
+		if fault.StatusCode, err = strconv.Atoi(status); err != nil || fault.StatusCode < 100 || fault.StatusCode > 599 {
+			return fault, fmt.Errorf("invalid status code %q", status)
+		}
+	}
+	if malformed != "" {
+		if fault.Malformed, err = strconv.ParseBool(malformed); err != nil {
+			return fault, fmt.Errorf("invalid malformed flag %q", malformed)
+		}
+	}
+	if rate != "" {
+		if fault.Rate, err = strconv.ParseFloat(rate, 64); err != nil || fault.Rate < 0 || fault.Rate > 1 {
+			return fault, fmt.Errorf("invalid rate %q: must be between 0 and 1", rate)
+		}
+	}
+	if count != "" {
+		if fault.Count, err = strconv.Atoi(count); err != nil || fault.Count < 0 {
+			return fault, fmt.Errorf("invalid count %q", count)
+		}
+	}
+
+	return fault, nil
+}
+
+func indexed(players []Player) map[string]Player {
+	byIndex := make(map[string]Player, len(players))
+	for i, player := range players {
+		byIndex[strconv.Itoa(i)] = player
+	}
+	return byIndex
+}
+
+func removePlayer(players []Player, uniqueID string) []Player {
+	kept := players[:0]
+	for _, player := range players {
+		if player.UniqueID != uniqueID {
+			kept = append(kept, player)
+		}
+	}
+	return kept
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}, message string, succeeded bool) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Data      interface{} `json:"data"`
+		Message   string      `json:"message"`
+		Succeeded bool        `json:"succeeded"`
+	}{data, message, succeeded})
+}
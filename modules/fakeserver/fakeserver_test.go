@@ -0,0 +1,81 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type response struct {
+	Data      json.RawMessage `json:"data"`
+	Message   string          `json:"message"`
+	Succeeded bool            `json:"succeeded"`
+}
+
+func request(t *testing.T, s *Server, method, target string) (int, response) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+
+	var resp response
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s: invalid JSON %q: %v", method, target, recorder.Body.String(), err)
+	}
+	return recorder.Code, resp
+}
+
+func TestEndpointChecks(t *testing.T) {
+	s := New("pw")
+
+	tests := []struct {
+		method string
+		target string
+		status int
+	}{
+		{http.MethodGet, "/player/count?password=pw", http.StatusOK},
+		{http.MethodGet, "/player/count?password=nope", http.StatusUnauthorized},
+		{http.MethodGet, "/player/count", http.StatusUnauthorized},
+		{http.MethodPost, "/player/count?password=pw", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/player/kick?password=pw&unique_id=1", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/player/kick?password=pw", http.StatusBadRequest},
+		{http.MethodPost, "/player/ban?password=pw&unique_id=1&hours=x", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		if status, _ := request(t, s, tt.method, tt.target); status != tt.status {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.target, status, tt.status)
+		}
+	}
+}
+
+func TestFaultCount(t *testing.T) {
+	s := New("pw")
+	s.InjectFault("/version", Fault{StatusCode: http.StatusBadGateway, Count: 2})
+
+	for i, want := range []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusOK} {
+		if status, _ := request(t, s, http.MethodGet, "/version?password=pw"); status != want {
+			t.Fatalf("request %d: status %d, want %d", i+1, status, want)
+		}
+	}
+}
+
+func TestExpiredBansArePruned(t *testing.T) {
+	s := New("pw")
+	s.bans = []Player{
+		{Name: "Old", UniqueID: "1", ExpireTime: time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)},
+		{Name: "Timed", UniqueID: "2", ExpireTime: time.Now().Add(time.Hour).UTC().Format(time.RFC3339)},
+		{Name: "Forever", UniqueID: "3"},
+	}
+
+	bans := s.Bans()
+	if len(bans) != 2 || bans[0].UniqueID != "2" || bans[1].UniqueID != "3" {
+		t.Fatalf("Bans() = %+v, want Timed and Forever", bans)
+	}
+
+	if _, resp := request(t, s, http.MethodPost, "/player/unban?password=pw&unique_id=1"); resp.Succeeded {
+		t.Fatalf("unbanning an expired ban succeeded: %q", resp.Message)
+	}
+}
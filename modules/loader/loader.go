@@ -1,8 +1,12 @@
 package loader
 
 import (
+	"motor-town-server-tool/modules/commands/check"
+	"motor-town-server-tool/modules/commands/configcmd"
 	"motor-town-server-tool/modules/commands/configure"
 	"motor-town-server-tool/modules/commands/connect"
+	"motor-town-server-tool/modules/commands/exec"
+	"motor-town-server-tool/modules/commands/fakeserver"
 )
 
 type Commander interface {
@@ -14,11 +18,23 @@ type Commander interface {
 func LoadCommands() map[string]Commander {
 	commands := make(map[string]Commander)
 
+	checkCmd := &check.Command{}
+	commands[checkCmd.Name()] = checkCmd
+
+	configCmd := &configcmd.Command{}
+	commands[configCmd.Name()] = configCmd
+
 	configureCmd := &configure.Command{}
 	commands[configureCmd.Name()] = configureCmd
 
 	connectCmd := &connect.Command{}
 	commands[connectCmd.Name()] = connectCmd
 
+	execCmd := &exec.Command{}
+	commands[execCmd.Name()] = execCmd
+
+	fakeServerCmd := &fakeserver.Command{}
+	commands[fakeServerCmd.Name()] = fakeServerCmd
+
 	return commands
 }
@@ -0,0 +1,76 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Format string
+
+const (
+	Table Format = "table"
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+	CSV   Format = "csv"
+)
+
+var current = Table
+
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(value)); format {
+	case Table, JSON, YAML, CSV:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported output format %q (expected table, json, yaml or csv)", value)
+	}
+}
+
+func SetFormat(format Format) {
+	current = format
+}
+
+func CurrentFormat() Format {
+	return current
+}
+
+type Result struct {
+	Data   interface{}
+	Header []string
+	Rows   [][]string
+	Human  func(w io.Writer)
+}
+
+func Render(w io.Writer, format Format, result Result) error {
+	switch format {
+	case JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result.Data)
+	case YAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(result.Data); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case CSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(result.Header); err != nil {
+			return err
+		}
+		if err := writer.WriteAll(result.Rows); err != nil {
+			return err
+		}
+		return writer.Error()
+	default:
+		if result.Human != nil {
+			result.Human(w)
+		}
+		return nil
+	}
+}
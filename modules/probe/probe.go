@@ -0,0 +1,108 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"motor-town-server-tool/modules/api"
+	"motor-town-server-tool/modules/types"
+)
+
+const DefaultTimeout = 5 * time.Second
+
+type Status string
+
+const (
+	StatusOK           Status = "ok"
+	StatusUnauthorized Status = "unauthorized"
+	StatusUnreachable  Status = "unreachable"
+	StatusCertificate  Status = "certificate"
+	StatusError        Status = "error"
+)
+
+// Result describes a single health check of an instance: whether the web
+// API answered, whether the password was accepted and what it reported.
+type Result struct {
+	Status        Status
+	Reachable     bool
+	Authenticated bool
+	Version       string
+	PlayerCount   int
+	Latency       time.Duration
+	Err           error
+}
+
+func (r Result) OK() bool {
+	return r.Status == StatusOK
+}
+
+func (r Result) Summary() string {
+	switch r.Status {
+	case StatusOK:
+		return fmt.Sprintf("reachable, authenticated, version %s, %d players online (%s)", r.Version, r.PlayerCount, r.Latency.Round(time.Millisecond))
+	case StatusUnauthorized:
+		return fmt.Sprintf("reachable, but authentication failed: %v", r.Err)
+	case StatusUnreachable:
+		return fmt.Sprintf("unreachable: %v", r.Err)
+	case StatusCertificate:
+		return fmt.Sprintf("reachable, but the TLS certificate was rejected: %v", r.Err)
+	default:
+		if r.Reachable {
+			return fmt.Sprintf("reachable, but the server returned an error: %v", r.Err)
+		}
+		return fmt.Sprintf("check failed: %v", r.Err)
+	}
+}
+
+// Run asks the instance for its version and player count without retries,
+// so a misconfigured instance is reported quickly.
+func Run(ctx context.Context, instance types.Instance) Result {
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	client, err := api.NewClient(instance, api.WithRetryPolicy(api.NoRetry))
+	if err != nil {
+		return Result{Status: StatusError, Err: err}
+	}
+
+	start := time.Now()
+
+	version, err := client.GetVersion(ctx)
+	if err != nil {
+		return failed(err, time.Since(start))
+	}
+
+	count, err := client.GetPlayerCount(ctx)
+	if err != nil {
+		return failed(err, time.Since(start))
+	}
+
+	return Result{
+		Status:        StatusOK,
+		Reachable:     true,
+		Authenticated: true,
+		Version:       version,
+		PlayerCount:   count,
+		Latency:       time.Since(start),
+	}
+}
+
+func failed(err error, latency time.Duration) Result {
+	result := Result{Status: StatusError, Reachable: true, Latency: latency, Err: err}
+
+	switch {
+	case errors.Is(err, api.ErrUnauthorized):
+		result.Status = StatusUnauthorized
+	case errors.Is(err, api.ErrCertificate):
+		result.Status = StatusCertificate
+	case errors.Is(err, api.ErrUnreachable), errors.Is(err, context.DeadlineExceeded):
+		result.Status = StatusUnreachable
+		result.Reachable = false
+	case errors.Is(err, context.Canceled):
+		result.Reachable = false
+	}
+
+	return result
+}
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// IsURL reports whether the instance address is a full base URL such as
+// https://mt.example.org/api, in which case Port is ignored.
+func (i Instance) IsURL() bool {
+	return strings.Contains(i.IP, "://")
+}
+
+// Address returns the instance address for display: the base URL, or
+// host:port with IPv6 addresses in brackets.
+func (i Instance) Address() string {
+	if i.IsURL() {
+		return i.IP
+	}
+	return net.JoinHostPort(strings.Trim(i.IP, "[]"), strconv.Itoa(i.Port))
+}
+
+// BaseURL returns the URL that API endpoint paths are appended to.
+func (i Instance) BaseURL() string {
+	if i.IsURL() {
+		return strings.TrimRight(i.IP, "/")
+	}
+	return i.URLScheme() + "://" + i.Address()
+}
+
+// URLScheme returns the scheme used to reach the instance. Without an
+// explicit scheme, instances with a [tls] table use https.
+func (i Instance) URLScheme() string {
+	if i.IsURL() {
+		scheme, _, _ := strings.Cut(i.IP, "://")
+		return strings.ToLower(scheme)
+	}
+	if i.Scheme != "" {
+		return strings.ToLower(i.Scheme)
+	}
+	if i.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
@@ -1,7 +1,77 @@
 package types
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 type Instance struct {
-	IP       string `toml:"ip"`
-	Port     int    `toml:"port"`
-	Password string `toml:"password"`
+	IP              string       `toml:"ip"`
+	Port            int          `toml:"port,omitzero"`
+	Scheme          string       `toml:"scheme,omitempty"`
+	Password        string       `toml:"password,omitempty"`
+	PasswordEnv     string       `toml:"password_env,omitempty"`
+	PasswordFile    string       `toml:"password_file,omitempty"`
+	PasswordCommand string       `toml:"password_command,omitempty"`
+	Description     string       `toml:"description,omitempty"`
+	Group           string       `toml:"group,omitempty"`
+	Region          string       `toml:"region,omitempty"`
+	Tags            []string     `toml:"tags,omitempty"`
+	Retry           *RetryConfig `toml:"retry,omitempty"`
+	TLS             *TLSConfig   `toml:"tls,omitempty"`
+}
+
+type RetryConfig struct {
+	MaxAttempts    int           `toml:"max_attempts,omitzero"`
+	InitialBackoff time.Duration `toml:"initial_backoff,omitzero"`
+	MaxBackoff     time.Duration `toml:"max_backoff,omitzero"`
+	RetryPOST      bool          `toml:"retry_post,omitempty"`
+}
+
+type TLSConfig struct {
+	CAFile             string `toml:"ca_file,omitempty"`
+	CertFile           string `toml:"cert_file,omitempty"`
+	KeyFile            string `toml:"key_file,omitempty"`
+	InsecureSkipVerify bool   `toml:"insecure_skip_verify,omitempty"`
+}
+
+func (i Instance) PasswordSource() string {
+	switch {
+	case i.PasswordEnv != "":
+		return "environment variable " + i.PasswordEnv
+	case i.PasswordFile != "":
+		return "file " + i.PasswordFile
+	case i.PasswordCommand != "":
+		return "command"
+	default:
+		return ""
+	}
+}
+
+func (i Instance) HasTag(tag string) bool {
+	for _, t := range i.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (i Instance) Labels() string {
+	var labels []string
+	if i.Group != "" {
+		labels = append(labels, "group: "+i.Group)
+	}
+	if i.Region != "" {
+		labels = append(labels, "region: "+i.Region)
+	}
+	if len(i.Tags) > 0 {
+		labels = append(labels, "tags: "+strings.Join(i.Tags, ", "))
+	}
+
+	if len(labels) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" [%s]", strings.Join(labels, "; "))
 }